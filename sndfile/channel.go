@@ -0,0 +1,266 @@
+package sndfile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unsafe"
+)
+
+// ChannelPosition is where a channel is meant to be played, one of the ChannelMap constants.
+type ChannelPosition int32
+
+var channelNames = map[ChannelPosition]string{
+	ChannelMapInvalid:            "Invalid",
+	ChannelMapMono:               "Mono",
+	ChannelMapLeft:               "Left",
+	ChannelMapRight:              "Right",
+	ChannelMapCenter:             "Center",
+	ChannelMapFrontLeft:          "FrontLeft",
+	ChannelMapFrontRight:         "FrontRight",
+	ChannelMapFrontCenter:        "FrontCenter",
+	ChannelMapRearCenter:         "RearCenter",
+	ChannelMapRearLeft:           "RearLeft",
+	ChannelMapRearRight:          "RearRight",
+	ChannelMapLfe:                "Lfe",
+	ChannelMapFrontLeftOfCenter:  "FrontLeftOfCenter",
+	ChannelMapFrontRightOfCenter: "FrontRightOfCenter",
+	ChannelMapSideLeft:           "SideLeft",
+	ChannelMapSideRight:          "SideRight",
+	ChannelMapTopCenter:          "TopCenter",
+	ChannelMapTopFrontLeft:       "TopFrontLeft",
+	ChannelMapTopFrontRight:      "TopFrontRight",
+	ChannelMapTopFrontCenter:     "TopFrontCenter",
+	ChannelMapTopRearLeft:        "TopRearLeft",
+	ChannelMapTopRearRight:       "TopRearRight",
+	ChannelMapTopRearCenter:      "TopRearCenter",
+	ChannelMapAmbisonicBW:        "AmbisonicBW",
+	ChannelMapAmbisonicBX:        "AmbisonicBX",
+	ChannelMapAmbisonicBY:        "AmbisonicBY",
+	ChannelMapAmbisonicBZ:        "AmbisonicBZ",
+}
+
+// String returns the name of the ChannelMap constant without its prefix, e.g. "FrontLeft".
+func (p ChannelPosition) String() string {
+	if s, ok := channelNames[p]; ok {
+		return s
+	}
+	return fmt.Sprintf("ChannelPosition(%d)", int32(p))
+}
+
+// alias returns the position libsndfile uses for the same speaker in the other naming scheme: plain Left, Right and Center come from formats without surround positions, where a WAVEX file says FrontLeft, FrontRight and FrontCenter.
+func (p ChannelPosition) alias() ChannelPosition {
+	switch p {
+	case ChannelMapLeft:
+		return ChannelMapFrontLeft
+	case ChannelMapRight:
+		return ChannelMapFrontRight
+	case ChannelMapCenter:
+		return ChannelMapFrontCenter
+	case ChannelMapFrontLeft:
+		return ChannelMapLeft
+	case ChannelMapFrontRight:
+		return ChannelMapRight
+	case ChannelMapFrontCenter:
+		return ChannelMapCenter
+	}
+	return p
+}
+
+// ChannelLayout lists the position of each channel, in the order the channels are stored.
+type ChannelLayout []ChannelPosition
+
+// Standard layouts, in the order WAVEX files store them.
+var (
+	StereoLayout     = ChannelLayout{ChannelMapFrontLeft, ChannelMapFrontRight}
+	Surround51Layout = ChannelLayout{ChannelMapFrontLeft, ChannelMapFrontRight, ChannelMapFrontCenter, ChannelMapLfe, ChannelMapRearLeft, ChannelMapRearRight}
+	Surround71Layout = ChannelLayout{ChannelMapFrontLeft, ChannelMapFrontRight, ChannelMapFrontCenter, ChannelMapLfe, ChannelMapRearLeft, ChannelMapRearRight, ChannelMapSideLeft, ChannelMapSideRight}
+	AmbisonicBLayout = ChannelLayout{ChannelMapAmbisonicBW, ChannelMapAmbisonicBX, ChannelMapAmbisonicBY, ChannelMapAmbisonicBZ}
+)
+
+// String returns the channel names separated by spaces.
+func (l ChannelLayout) String() string {
+	names := make([]string, len(l))
+	for i, p := range l {
+		names[i] = p.String()
+	}
+	return strings.Join(names, " ")
+}
+
+// Index returns the channel number at position p, or -1 if l has no such channel. Left and FrontLeft are treated as the same position, as are Right and FrontRight and Center and FrontCenter, but an exact match is preferred.
+func (l ChannelLayout) Index(p ChannelPosition) int {
+	for _, want := range []ChannelPosition{p, p.alias()} {
+		for i, q := range l {
+			if q == want {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// Equal reports whether l and m have the same positions in the same order.
+func (l ChannelLayout) Equal(m ChannelLayout) bool {
+	if len(l) != len(m) {
+		return false
+	}
+	for i := range l {
+		if l[i] != m[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// ChannelLayout returns the position of each channel in the file, from its channel map. Mono and stereo files without a channel map are taken to be Mono and Left, Right.
+func (f *File) ChannelLayout() (ChannelLayout, error) {
+	m, err := f.GetChannelMapInfo()
+	if err == ErrClosed {
+		return nil, err
+	}
+	if err != nil {
+		switch len(m) {
+		case 1:
+			return ChannelLayout{ChannelMapMono}, nil
+		case 2:
+			return ChannelLayout{ChannelMapLeft, ChannelMapRight}, nil
+		}
+		return nil, errors.New("ChannelLayout: file has no channel map")
+	}
+	l := make(ChannelLayout, len(m))
+	for i, p := range m {
+		l[i] = ChannelPosition(p)
+	}
+	return l, nil
+}
+
+// SetChannelLayout is SetChannelMapInfo for a ChannelLayout.
+func (f *File) SetChannelLayout(l ChannelLayout) error {
+	m := make([]int32, len(l))
+	for i, p := range l {
+		m[i] = int32(p)
+	}
+	return f.SetChannelMapInfo(m)
+}
+
+// ChannelReader reads some of the channels of a file, picked by position, in the order they were asked for.
+type ChannelReader struct {
+	f        *File
+	layout   ChannelLayout
+	src      []int // file channel for each channel read
+	channels int   // in the file
+}
+
+// SelectChannels returns a reader for the channels of f at the positions in want, whatever order the file stores them in. For example, SelectChannels(StereoLayout) reads just the front pair of a 5.1 file. It is an error if f has no channel at one of the positions.
+func (f *File) SelectChannels(want ChannelLayout) (*ChannelReader, error) {
+	if len(want) == 0 {
+		return nil, errors.New("SelectChannels: no channels selected")
+	}
+	l, err := f.ChannelLayout()
+	if err != nil {
+		return nil, err
+	}
+	r := &ChannelReader{f: f, layout: append(ChannelLayout(nil), want...), src: make([]int, len(want)), channels: len(l)}
+	for i, p := range want {
+		if r.src[i] = l.Index(p); r.src[i] < 0 {
+			return nil, fmt.Errorf("SelectChannels: file has no %v channel, only %v", p, l)
+		}
+	}
+	return r, nil
+}
+
+// Layout returns the positions of the channels r reads, in order.
+func (r *ChannelReader) Layout() ChannelLayout {
+	return append(ChannelLayout(nil), r.layout...)
+}
+
+// ReadFrames is File.ReadFrames for the selected channels: out holds len(r.Layout()) samples per frame, and the other channels are skipped over. Returns the number of frames read.
+func (r *ChannelReader) ReadFrames(out interface{}) (read int64, err error) {
+	if err = r.f.lock(); err != nil {
+		return
+	}
+	defer r.f.mu.Unlock()
+	return r.readFrames(out)
+}
+
+// readFrames is ReadFrames for callers that hold the file's lock.
+func (r *ChannelReader) readFrames(out interface{}) (read int64, err error) {
+	k := len(r.src)
+	switch b := out.(type) {
+	case []uint16:
+		return r.readFrames(*(*[]int16)(unsafe.Pointer(&b)))
+	case []uint32:
+		return r.readFrames(*(*[]int32)(unsafe.Pointer(&b)))
+	case []int16:
+		buf := make([]int16, minInt(len(b)/k, planarChunk)*r.channels)
+		return r.read(len(b)/k, func(n int) (int64, error) {
+			return r.f.readFramesInt16Locked(buf[:n*r.channels])
+		}, func(start, n int) {
+			for i := 0; i < n; i++ {
+				for j, c := range r.src {
+					b[(start+i)*k+j] = buf[i*r.channels+c]
+				}
+			}
+		})
+	case []int32:
+		buf := make([]int32, minInt(len(b)/k, planarChunk)*r.channels)
+		return r.read(len(b)/k, func(n int) (int64, error) {
+			return r.f.readFramesInt32Locked(buf[:n*r.channels])
+		}, func(start, n int) {
+			for i := 0; i < n; i++ {
+				for j, c := range r.src {
+					b[(start+i)*k+j] = buf[i*r.channels+c]
+				}
+			}
+		})
+	case []float32:
+		buf := make([]float32, minInt(len(b)/k, planarChunk)*r.channels)
+		return r.read(len(b)/k, func(n int) (int64, error) {
+			return r.f.readFramesFloat32Locked(buf[:n*r.channels])
+		}, func(start, n int) {
+			for i := 0; i < n; i++ {
+				for j, c := range r.src {
+					b[(start+i)*k+j] = buf[i*r.channels+c]
+				}
+			}
+		})
+	case []float64:
+		buf := make([]float64, minInt(len(b)/k, planarChunk)*r.channels)
+		return r.read(len(b)/k, func(n int) (int64, error) {
+			return r.f.readFramesFloat64Locked(buf[:n*r.channels])
+		}, func(start, n int) {
+			for i := 0; i < n; i++ {
+				for j, c := range r.src {
+					b[(start+i)*k+j] = buf[i*r.channels+c]
+				}
+			}
+		})
+	}
+	return -1, errors.New("Unsupported type in read buffer, needs (u)int16, (u)int32, or float type")
+}
+
+// read fills frames frames of output a chunk at a time: fill reads up to n frames of every channel into a buffer, and pick copies n frames of the selected channels from it to the output starting at frame start.
+func (r *ChannelReader) read(frames int, fill func(n int) (int64, error), pick func(start, n int)) (read int64, err error) {
+	if frames < 1 {
+		return 0, io.EOF
+	}
+	for read < int64(frames) {
+		want := minInt(frames-int(read), planarChunk)
+		n, err := fill(want)
+		if n > 0 {
+			pick(int(read), int(n))
+			read += n
+		}
+		if err != nil {
+			if read > 0 && err == io.EOF {
+				err = nil
+			}
+			return read, err
+		}
+		if n < int64(want) {
+			break
+		}
+	}
+	return read, nil
+}
@@ -0,0 +1,138 @@
+package sndfile
+
+import (
+	"os"
+	"testing"
+)
+
+func TestChannelLayoutNames(t *testing.T) {
+	if s := Surround51Layout.String(); s != "FrontLeft FrontRight FrontCenter Lfe RearLeft RearRight" {
+		t.Errorf("5.1 prints as %q", s)
+	}
+	if s := ChannelPosition(ChannelMapAmbisonicBW).String(); s != "AmbisonicBW" {
+		t.Errorf("B-format W prints as %q", s)
+	}
+	if s := ChannelPosition(ChannelMapMax).String(); s != "ChannelPosition(27)" {
+		t.Errorf("ChannelMapMax prints as %q", s)
+	}
+	for p := ChannelPosition(ChannelMapInvalid); p < ChannelMapMax; p++ {
+		if _, ok := channelNames[p]; !ok {
+			t.Errorf("position %d has no name", p)
+		}
+	}
+}
+
+func TestChannelLayoutIndex(t *testing.T) {
+	l := ChannelLayout{ChannelMapLfe, ChannelMapRight, ChannelMapLeft, ChannelMapFrontLeft}
+	tests := []struct {
+		p ChannelPosition
+		i int
+	}{
+		{ChannelMapLfe, 0},
+		{ChannelMapLeft, 2},
+		{ChannelMapFrontLeft, 3},
+		{ChannelMapFrontRight, 1},
+		{ChannelMapCenter, -1},
+		{ChannelMapRearLeft, -1},
+	}
+	for _, test := range tests {
+		if i := l.Index(test.p); i != test.i {
+			t.Errorf("%v is channel %d of %v, expected %d", test.p, i, l, test.i)
+		}
+	}
+	if !Surround71Layout[:6].Equal(Surround51Layout) || StereoLayout.Equal(Surround51Layout[:3]) {
+		t.Error("Equal is wrong")
+	}
+}
+
+func TestSelectChannels(t *testing.T) {
+	defer os.Remove("channels.wav")
+	const n = 5000
+	i := Info{Channels: 6, Samplerate: 48000, Format: SF_FORMAT_WAVEX | SF_FORMAT_PCM_16}
+	f, err := Open("channels.wav", Write, &i)
+	if err != nil {
+		t.Fatal("couldn't open file for write", err)
+	}
+	if err = f.SetChannelLayout(Surround51Layout); err != nil {
+		t.Fatal("SetChannelLayout failed", err)
+	}
+	// each sample holds its channel and frame
+	in := make([]int16, 6*n)
+	for k := range in {
+		in[k] = int16(k%6*n + k/6)
+	}
+	if w, err := f.WriteFrames(in); w != n || err != nil {
+		t.Fatalf("only wrote %d of %d frames: %v", w, n, err)
+	}
+	f.Close()
+
+	f, err = Open("channels.wav", Read, &i)
+	if err != nil {
+		t.Fatal("couldn't open file for read", err)
+	}
+	defer f.Close()
+	l, err := f.ChannelLayout()
+	if err != nil || !l.Equal(Surround51Layout) {
+		t.Fatalf("layout read back as %v: %v", l, err)
+	}
+	if _, err = f.SelectChannels(AmbisonicBLayout); err == nil {
+		t.Error("selected B-format channels from a 5.1 file")
+	}
+	want := ChannelLayout{ChannelMapFrontRight, ChannelMapLfe, ChannelMapLeft}
+	r, err := f.SelectChannels(want)
+	if err != nil {
+		t.Fatal("SelectChannels failed", err)
+	}
+	if !r.Layout().Equal(want) {
+		t.Errorf("reader layout is %v, expected %v", r.Layout(), want)
+	}
+	src := []int{1, 3, 0}
+	out := make([]int16, 3*(n+10))
+	if got, err := r.ReadFrames(out); got != n || err != nil {
+		t.Fatalf("read %d of %d frames: %v", got, n, err)
+	}
+	for k, v := range out[:3*n] {
+		if e := int16(src[k%3]*n + k/3); v != e {
+			t.Fatalf("sample %d is %d, expected %d", k, v, e)
+		}
+	}
+
+	if _, err = f.Seek(0, Set); err != nil {
+		t.Fatal("Seek failed", err)
+	}
+	out64 := make([]float64, 2*n)
+	if r, err = f.SelectChannels(StereoLayout); err != nil {
+		t.Fatal("SelectChannels failed", err)
+	}
+	if got, err := r.ReadFrames(out64); got != n || err != nil {
+		t.Fatalf("read %d of %d stereo frames: %v", got, n, err)
+	}
+	if e := float64(n+7) / 32768; out64[2*7+1] != e {
+		t.Errorf("FrontRight frame 7 is %v, expected %v", out64[2*7+1], e)
+	}
+}
+
+func TestChannelLayoutDefault(t *testing.T) {
+	defer os.Remove("channels.wav")
+	i := Info{Channels: 2, Samplerate: 48000, Format: SF_FORMAT_WAV | SF_FORMAT_PCM_16}
+	f, err := Open("channels.wav", Write, &i)
+	if err != nil {
+		t.Fatal("couldn't open file for write", err)
+	}
+	f.WriteFrames([]int16{1, 2, 3, 4})
+	f.Close()
+
+	f, err = Open("channels.wav", Read, &i)
+	if err != nil {
+		t.Fatal("couldn't open file for read", err)
+	}
+	defer f.Close()
+	r, err := f.SelectChannels(ChannelLayout{ChannelMapFrontRight})
+	if err != nil {
+		t.Fatal("couldn't select the right channel of a plain stereo file", err)
+	}
+	out := make([]int32, 4)
+	if got, err := r.ReadFrames(out); got != 2 || err != nil || out[0] != 2<<16 || out[1] != 4<<16 {
+		t.Errorf("read %d frames, %v: %v", got, out, err)
+	}
+}
@@ -0,0 +1,136 @@
+// +build !legacy
+
+package sndfile
+
+// #cgo pkg-config: sndfile
+// #include <stdlib.h>
+// #include <string.h>
+// #include <sndfile.h>
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"unsafe"
+)
+
+// A Chunk is a raw chunk from the header of a RIFF/WAV, AIFF or related file, such as "iXML", "axml" or "LIST".
+type Chunk struct {
+	ID   string
+	Data []byte
+}
+
+// A ChunkIterator walks the chunks of a file returned by File.Chunks. It is only valid until the file is closed.
+type ChunkIterator struct {
+	f       *File
+	id      string
+	it      *C.SF_CHUNK_ITERATOR
+	started bool
+	chunk   Chunk
+	err     error
+}
+
+// Chunks returns an iterator over the chunks in the file header with the given ID, or over all the chunks libsndfile keeps track of if id is empty. Call Next to advance to the first chunk, and check Err once Next returns false.
+// The chunk API needs libsndfile 1.0.26 or later, so with the legacy build tag the iterator never returns a chunk and Err reports that.
+func (f *File) Chunks(id string) *ChunkIterator {
+	return &ChunkIterator{f: f, id: id}
+}
+
+func chunkInfo(id string) (ci C.SF_CHUNK_INFO, err error) {
+	if len(id) >= len(ci.id) {
+		return ci, errors.New("chunk id too long: " + id)
+	}
+	arrFromGoString(ci.id[:], id)
+	ci.id_size = C.uint(len(id))
+	return
+}
+
+// Next advances to the next matching chunk and reads its data. It returns false when there are no more chunks or an error occurred.
+func (it *ChunkIterator) Next() bool {
+	if it.err != nil || (it.started && it.it == nil) {
+		return false
+	}
+	if it.err = it.f.lock(); it.err != nil {
+		return false
+	}
+	defer it.f.mu.Unlock()
+	if it.started {
+		it.it = C.sf_next_chunk_iterator(it.it)
+	} else {
+		it.started = true
+		if it.id == "" {
+			it.it = C.sf_get_chunk_iterator(it.f.s, nil)
+		} else {
+			var ci C.SF_CHUNK_INFO
+			if ci, it.err = chunkInfo(it.id); it.err != nil {
+				return false
+			}
+			it.it = C.sf_get_chunk_iterator(it.f.s, &ci)
+		}
+	}
+	if it.it == nil {
+		return false
+	}
+
+	var ci C.SF_CHUNK_INFO
+	if r := C.sf_get_chunk_size(it.it, &ci); r != C.SF_ERR_NO_ERROR {
+		it.err = it.f.codeError("Chunks", r)
+		return false
+	}
+	// C.GoBytes takes an int, so bigger chunks would be cut short
+	size := ci.datalen
+	if uint64(size) > math.MaxInt32 {
+		it.err = fmt.Errorf("Chunks: chunk is %d bytes, too large to read", size)
+		return false
+	}
+	// the data pointer lives inside ci, which cgo passes to C, so it has to be C memory
+	ci.data = C.malloc(C.size_t(size) + 1)
+	defer C.free(ci.data)
+	if r := C.sf_get_chunk_data(it.it, &ci); r != C.SF_ERR_NO_ERROR {
+		it.err = it.f.codeError("Chunks", r)
+		return false
+	}
+	it.chunk.ID = C.GoStringN(&ci.id[0], C.int(ci.id_size))
+	it.chunk.Data = C.GoBytes(ci.data, C.int(size))
+	return true
+}
+
+// Chunk returns the chunk read by the last successful call to Next.
+func (it *ChunkIterator) Chunk() Chunk {
+	return it.chunk
+}
+
+// Err returns the error, if any, that stopped iteration.
+func (it *ChunkIterator) Err() error {
+	return it.err
+}
+
+// SetChunk adds a chunk with the given ID and data to the header of a file opened for writing. This must be called before any audio data is written. Chunks libsndfile writes itself, such as "fmt " and "data", cannot be set.
+func (f *File) SetChunk(id string, data []byte) (err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	return f.setChunkLocked(id, data)
+}
+
+func (f *File) setChunkLocked(id string, data []byte) (err error) {
+	ci, err := chunkInfo(id)
+	if err != nil {
+		return
+	}
+	if uint64(len(data)) > math.MaxUint32 {
+		return fmt.Errorf("SetChunk: %q chunk is %d bytes, too large for a chunk", id, len(data))
+	}
+	ci.datalen = C.uint(len(data))
+	ci.data = C.malloc(C.size_t(len(data)) + 1)
+	defer C.free(ci.data)
+	if len(data) > 0 {
+		C.memcpy(ci.data, unsafe.Pointer(&data[0]), C.size_t(len(data)))
+	}
+	if r := C.sf_set_chunk(f.s, &ci); r != C.SF_ERR_NO_ERROR {
+		err = f.codeError("SetChunk", r)
+	}
+	return
+}
@@ -0,0 +1,73 @@
+// +build !legacy
+
+package sndfile
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestChunks(t *testing.T) {
+	var i Info
+	i.Format = SF_FORMAT_WAV | SF_FORMAT_PCM_16
+	i.Channels = 1
+	i.Samplerate = 8000
+	f, err := Open("chunks.wav", Write, &i)
+	if err != nil {
+		t.Fatal("couldn't open file for write", err)
+	}
+	ixml := []byte("<BWFXML><PROJECT>gosndfile</PROJECT></BWFXML>\n")
+	vendor := []byte{0xde, 0xad, 0xbe, 0xef}
+	if err = f.SetChunk("iXML", ixml); err != nil {
+		t.Fatal("SetChunk failed", err)
+	}
+	if err = f.SetChunk("gsf1", vendor); err != nil {
+		t.Fatal("SetChunk failed", err)
+	}
+	if f.SetChunk("much too long for a chunk id, which only has room for sixty three bytes", vendor) == nil {
+		t.Error("long chunk id should be rejected")
+	}
+	f.WriteItems(make([]int16, 100))
+	f.Close()
+
+	f, err = Open("chunks.wav", Read, &i)
+	if err != nil {
+		t.Fatal("couldn't open file for read", err)
+	}
+	it := f.Chunks("iXML")
+	n := 0
+	for it.Next() {
+		c := it.Chunk()
+		if c.ID != "iXML" || !bytes.Equal(c.Data, ixml) {
+			t.Errorf("wrong chunk %q %q", c.ID, c.Data)
+		}
+		n++
+	}
+	if it.Err() != nil {
+		t.Error("iteration failed", it.Err())
+	}
+	if n != 1 {
+		t.Errorf("found %d iXML chunks, expected 1", n)
+	}
+
+	found := false
+	it = f.Chunks("")
+	for it.Next() {
+		c := it.Chunk()
+		if c.ID == "gsf1" {
+			found = bytes.Equal(c.Data, vendor)
+		}
+	}
+	if !found {
+		t.Error("vendor chunk not found when iterating over all chunks")
+	}
+
+	it = f.Chunks("nope")
+	if it.Next() {
+		t.Error("found a chunk that isn't there", it.Chunk())
+	}
+	f.Close()
+	if f.Chunks("iXML").Next() {
+		t.Error("iterating a closed file should fail")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"unicode/utf8"
 	"unsafe"
 )
 
@@ -27,6 +28,10 @@ func GetLibVersion() (s string, err error) {
 
 // Retrieve the log buffer generated when opening a file as a string. This log buffer can often contain a good reason for why libsndfile failed to open a particular file.
 func (f *File) GetLogInfo() (s string, err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
 	l := C.sf_command(f.s, C.SFC_GET_LOG_INFO, nil, 0)
 	c := make([]byte, l)
 	m := C.sf_command(f.s, C.SFC_GET_LOG_INFO, unsafe.Pointer(&c[0]), l)
@@ -40,46 +45,66 @@ func (f *File) GetLogInfo() (s string, err error) {
 
 // Retrieve the measured maximum signal value. This involves reading through the whole file which can be slow on large files.
 func (f *File) CalcSignalMax() (ret float64, err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
 	e := C.sf_command(f.s, C.SFC_CALC_SIGNAL_MAX, unsafe.Pointer(&ret), 8)
 	if e != 0 {
-		err = sErrorType(e)
+		err = f.codeError("CalcSignalMax", e)
 	}
 	return
 }
 
 // Retrieve the measured normalised maximum signal value. This involves reading through the whole file which can be slow on large files.
 func (f *File) CalcNormSignalMax() (ret float64, err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
 	e := C.sf_command(f.s, C.SFC_CALC_NORM_SIGNAL_MAX, unsafe.Pointer(&ret), 8)
 	if e != 0 {
-		err = sErrorType(e)
+		err = f.codeError("CalcNormSignalMax", e)
 	}
 	return
 }
 
 //Calculate the peak value (ie a single number) for each channel. This involves reading through the whole file which can be slow on large files.
 func (f *File) CalcMaxAllChannels() (ret []float64, err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
 	c := f.Format.Channels
 	ret = make([]float64, c)
 	e := C.sf_command(f.s, C.SFC_CALC_MAX_ALL_CHANNELS, unsafe.Pointer(&ret[0]), C.int(c*8))
 	if e != 0 {
-		err = sErrorType(e)
+		err = f.codeError("CalcMaxAllChannels", e)
 	}
 	return
 }
 
 //Calculate the normalised peak for each channel. This involves reading through the whole file which can be slow on large files.
 func (f *File) CalcNormMaxAllChannels() (ret []float64, err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
 	c := f.Format.Channels
 	ret = make([]float64, c)
 	e := C.sf_command(f.s, C.SFC_CALC_NORM_MAX_ALL_CHANNELS, unsafe.Pointer(&ret[0]), C.int(c*8))
 	if e != 0 {
-		err = sErrorType(e)
+		err = f.codeError("CalcNormMaxAllChannels", e)
 	}
 	return
 }
 
 //Retrieve the peak value for the file as stored in the file header.
 func (f *File) GetSignalMax() (ret float64, ok bool) {
+	if f.lock() != nil {
+		return
+	}
+	defer f.mu.Unlock()
 	r := C.sf_command(f.s, C.SFC_GET_SIGNAL_MAX, unsafe.Pointer(&ret), 8)
 	if r == C.SF_TRUE {
 		ok = true
@@ -89,6 +114,10 @@ func (f *File) GetSignalMax() (ret float64, ok bool) {
 
 //Retrieve the peak value for the file as stored in the file header.
 func (f *File) GetMaxAllChannels() (ret []float64, ok bool) {
+	if f.lock() != nil {
+		return
+	}
+	defer f.mu.Unlock()
 	c := f.Format.Channels
 	ret = make([]float64, c)
 	e := C.sf_command(f.s, C.SFC_GET_MAX_ALL_CHANNELS, unsafe.Pointer(&ret[0]), C.int(c*8))
@@ -154,6 +183,7 @@ func GetSimpleFormatCount() int {
 //Retrieve information about a simple format.
 //The value of the format argument should be the format number (ie 0 <= format <= count value obtained using GetSimpleFormatCount()).
 // The returned format argument is suitable for use in sndfile.Open()
+// SimpleFormats returns the whole list at once.
 func GetSimpleFormat(format int) (oformat int, name string, extension string, ok bool) {
 	var o C.SF_FORMAT_INFO
 	o.format = C.int(format)
@@ -184,6 +214,7 @@ func GetMajorFormatCount() int {
 
 //Retrieve information about a major format type
 //For a more comprehensive example, see the program list_formats.c in the examples/ directory of the libsndfile source code distribution.
+// MajorFormats returns the whole list at once.
 func GetMajorFormatInfo(format int) (oformat int, name string, extension string, ok bool) {
 	var o C.SF_FORMAT_INFO
 	o.format = C.int(format)
@@ -202,6 +233,7 @@ func GetSubFormatCount() int {
 }
 
 //Enumerate the subtypes (this function does not translate a subtype into a string describing that subtype). A typical use case might be retrieving a string description of all subtypes so that a dialog box can be filled in.
+// SubFormats returns the whole list at once, and SupportedSubtypes only those valid for a given major format.
 func GetSubFormatInfo(format int) (oformat int, name string, ok bool) {
 	var o C.SF_FORMAT_INFO
 	o.format = C.int(format)
@@ -211,6 +243,68 @@ func GetSubFormatInfo(format int) (oformat int, name string, ok bool) {
 	return
 }
 
+// FormatInfo describes a major format, subtype or simple format known to libsndfile. Extension is empty for subtypes.
+type FormatInfo struct {
+	Format    Format
+	Name      string
+	Extension string
+}
+
+// formatInfo runs one of the SFC_GET_*FORMAT* enumeration commands for the given index.
+func formatInfo(cmd C.int, index int) (fi FormatInfo, ok bool) {
+	var o C.SF_FORMAT_INFO
+	o.format = C.int(index)
+	if C.sf_command(nil, cmd, unsafe.Pointer(&o), C.int(unsafe.Sizeof(o))) != 0 {
+		return
+	}
+	fi.Format = Format(o.format)
+	fi.Name = C.GoString(o.name)
+	if o.extension != nil {
+		fi.Extension = C.GoString(o.extension)
+	}
+	return fi, true
+}
+
+// formatList collects every entry of one of the format enumerations.
+func formatList(count int, cmd C.int) (l []FormatInfo) {
+	for i := 0; i < count; i++ {
+		if fi, ok := formatInfo(cmd, i); ok {
+			l = append(l, fi)
+		}
+	}
+	return
+}
+
+// Returns every major format (container) supported by this build of libsndfile.
+func MajorFormats() []FormatInfo {
+	return formatList(GetMajorFormatCount(), C.SFC_GET_FORMAT_MAJOR)
+}
+
+// Returns every subtype (encoding) supported by this build of libsndfile.
+func SubFormats() []FormatInfo {
+	return formatList(GetSubFormatCount(), C.SFC_GET_FORMAT_SUBTYPE)
+}
+
+// Returns libsndfile's list of common major format and subtype combinations. Each Format is suitable for use in Open.
+func SimpleFormats() []FormatInfo {
+	return formatList(GetSimpleFormatCount(), C.SFC_GET_SIMPLE_FORMAT)
+}
+
+// Returns the subtypes that can be written in the given major format, found by checking each subtype against the major format with FormatCheck. Any subtype or endian bits in major are ignored.
+func SupportedSubtypes(major Format) (l []FormatInfo) {
+	major &= SF_FORMAT_TYPEMASK
+	for _, sub := range SubFormats() {
+		var i Info
+		i.Channels = 1
+		i.Samplerate = 48000
+		i.Format = major | sub.Format
+		if FormatCheck(i) {
+			l = append(l, sub)
+		}
+	}
+	return
+}
+
 //By default, WAV and AIFF files which contain floating point data (subtype SF_FORMAT_FLOAT or SF_FORMAT_DOUBLE) have a PEAK chunk. By using this command, the addition of a PEAK chunk can be turned on or off.
 
 //Note : This call must be made before any data is written to the file.
@@ -222,6 +316,10 @@ func (f *File) SetAddPeakChunk(set bool) bool {
 
 //There are however situations where large files are being generated and it would be nice to have valid data in the header before the file is complete. Using this command will update the file header to reflect the amount of data written to the file so far. Other programs opening the file for read (before any more data is written) will then read a valid sound file header.
 func (f *File) UpdateHeaderNow() {
+	if f.lock() != nil {
+		return
+	}
+	defer f.mu.Unlock()
 	C.sf_command(f.s, C.SFC_UPDATE_HEADER_NOW, nil, 0)
 }
 
@@ -232,15 +330,25 @@ func (f *File) SetUpdateHeaderAuto(set bool) bool {
 
 // Truncates a file to /count/ frames.  After this command, both the read and the write pointer will be at the new end of the file. This command will fail (returning non-zero) if the requested truncate position is beyond the end of the file.
 func (f *File) Truncate(count int64) (err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
 	r := C.sf_command(f.s, C.SFC_FILE_TRUNCATE, unsafe.Pointer(&count), 8)
 
 	if r != 0 {
-		err = errors.New(C.GoString(C.sf_strerror(f.s)))
+		err = f.error("Truncate")
+	} else {
+		f.Format.Frames = count
 	}
 	return
 }
 
 func (f *File) genericBoolBoolCmd(cmd C.int, i bool) bool {
+	if f.lock() != nil {
+		return false
+	}
+	defer f.mu.Unlock()
 	ib := C.SF_FALSE
 	if i {
 		ib = C.SF_TRUE
@@ -252,10 +360,14 @@ func (f *File) genericBoolBoolCmd(cmd C.int, i bool) bool {
 
 //Change the data start offset for files opened up as SF_FORMAT_RAW. libsndfile implements this but it appears to not do anything useful that you can't accomplish with seek, so consider this deprecated.
 func (f *File) SetRawStartOffset(count int64) (err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
 	r := C.sf_command(f.s, C.SFC_SET_RAW_START_OFFSET, unsafe.Pointer(&count), 8)
 
 	if r != 0 {
-		err = errors.New(C.GoString(C.sf_strerror(f.s)))
+		err = f.error("SetRawStartOffset")
 	}
 	return
 }
@@ -275,36 +387,76 @@ func (f *File) GetClipping(clip bool) bool {
 //The value of the length return value will be the length in bytes of the embedded file.
 // Untested.
 func (f *File) GetEmbeddedFileInfo() (offset, length int64, err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
 	var s C.SF_EMBED_FILE_INFO
 	r := C.sf_command(f.s, C.SFC_GET_EMBED_FILE_INFO, unsafe.Pointer(&s), C.int(unsafe.Sizeof(s)))
 	if r != 0 {
-		err = errors.New(C.GoString(C.sf_strerror(f.s)))
+		err = f.error("GetEmbeddedFileInfo")
 	}
 	offset = int64(s.offset)
 	length = int64(s.length)
 	return
 }
 
+// Have libsndfile rewrite an RF64 file as a plain WAV file on Close if the audio data turned out to be small enough to fit, so that only recordings over 4GiB need an RF64 reader. Has no effect on other formats.
+// This must be called on a file opened for Write before any audio data is written. See also OpenRF64Auto.
+func (f *File) SetRF64AutoDowngrade(downgrade bool) (err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	if f.written {
+		return errors.New("SetRF64AutoDowngrade: must be called before the first write")
+	}
+	d := C.int(C.SF_FALSE)
+	if downgrade {
+		d = C.SF_TRUE
+	}
+	if C.sf_command(f.s, C.SFC_RF64_AUTO_DOWNGRADE, nil, d) != d {
+		err = f.error("SetRF64AutoDowngrade")
+	}
+	return
+}
+
 const AmbisonicNone int = int(C.SF_AMBISONIC_NONE)
 const AmbisonicBFormat int = int(C.SF_AMBISONIC_B_FORMAT)
 
 //Test if the current file has the GUID of a WAVEX file for any of the Ambisonic formats.
 // returns AmbisonicNone or AmbisonicBFormat, or zero if the file format does not support Ambisonic formats
 func (f *File) WavexGetAmbisonic() int {
+	if f.lock() != nil {
+		return 0
+	}
+	defer f.mu.Unlock()
 	return int(C.sf_command(f.s, C.SFC_WAVEX_GET_AMBISONIC, nil, 0))
 }
 
 //Set the GUID of a new WAVEX file to indicate an Ambisonics format.
 // returns format that was just set, or zero if the file format does not support Ambisonic formats
 func (f *File) WavexSetAmbisonic(ambi int) int {
+	if f.lock() != nil {
+		return 0
+	}
+	defer f.mu.Unlock()
 	return int(C.sf_command(f.s, C.SFC_WAVEX_SET_AMBISONIC, nil, C.int(ambi)))
 }
 
-//Set the the Variable Bit Rate encoding quality. The encoding quality value should be between 0.0 (lowest quality) and 1.0 (highest quality). Untested.
+//Set the the Variable Bit Rate encoding quality. The encoding quality value should be between 0.0 (lowest quality) and 1.0 (highest quality).
+// This must be called before any audio data is written. Returns an error if the format has no variable bitrate encoder or the quality is rejected.
 func (f *File) SetVbrQuality(q float64) (err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	if f.written {
+		return errors.New("SetVbrQuality: must be called before the first write")
+	}
 	r := C.sf_command(f.s, C.SFC_SET_VBR_ENCODING_QUALITY, unsafe.Pointer(&q), 8)
-	if r != 0 {
-		err = errors.New(C.GoString(C.sf_strerror(f.s)))
+	if r != C.SF_TRUE {
+		err = f.error("SetVbrQuality")
 	}
 	return
 }
@@ -322,13 +474,30 @@ type BroadcastInfo struct {
 	Originator_reference string
 	Origination_date     string
 	Origination_time     string
-	Time_reference_low   uint32
-	Time_reference_high  uint32
+	TimeReference        uint64 // first sample's offset from midnight, in samples
 	Version              uint16
 	Umid                 string
+	LoudnessValue        int16 // integrated loudness in hundredths of LUFS (BWF version 2)
+	LoudnessRange        int16 // in hundredths of LU
+	MaxTruePeakLevel     int16 // in hundredths of dBTP
+	MaxMomentaryLoudness int16 // in hundredths of LUFS
+	MaxShortTermLoudness int16 // in hundredths of LUFS; the loudness fields need libsndfile 1.0.29 or later and are always zero with the legacy build tag
 	Coding_history       string
 }
 
+// BroadcastCodingHistoryMax is the longest BroadcastInfo.Coding_history that libsndfile will store.
+const BroadcastCodingHistoryMax = 16 * 1024
+
+// broadcastSize is the size of an SF_BROADCAST_INFO with room for historySize bytes of coding history, and never smaller than the fixed SF_BROADCAST_INFO.
+func broadcastSize(historySize int) C.size_t {
+	var c C.SF_BROADCAST_INFO
+	size := unsafe.Offsetof(c.coding_history) + uintptr(historySize)
+	if size < unsafe.Sizeof(c) {
+		size = unsafe.Sizeof(c)
+	}
+	return C.size_t(size)
+}
+
 func goStringFromArr(c []C.char) string {
 	s := make([]byte, len(c))
 	for i, r := range c {
@@ -349,9 +518,19 @@ func trim(in string) string {
 
 // Retrieve the Broadcast Extension Chunk from WAV (and related) files.
 func (f *File) GetBroadcastInfo() (bi *BroadcastInfo, ok bool) {
-	bic := new(C.SF_BROADCAST_INFO)
+	if f.lock() != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	return f.getBroadcastInfoLocked()
+}
 
-	r := C.sf_command(f.s, C.SFC_GET_BROADCAST_INFO, unsafe.Pointer(bic), C.int(unsafe.Sizeof(*bic)))
+func (f *File) getBroadcastInfoLocked() (bi *BroadcastInfo, ok bool) {
+	size := broadcastSize(BroadcastCodingHistoryMax)
+	bic := (*C.SF_BROADCAST_INFO)(C.calloc(1, size))
+	defer C.free(unsafe.Pointer(bic))
+
+	r := C.sf_command(f.s, C.SFC_GET_BROADCAST_INFO, unsafe.Pointer(bic), C.int(size))
 	if r == C.SF_TRUE {
 		bi = broadcastFromC(bic)
 		ok = true
@@ -359,25 +538,78 @@ func (f *File) GetBroadcastInfo() (bi *BroadcastInfo, ok bool) {
 	return
 }
 
+// arrFromGoString copies the bytes of src into arr, cutting it short if it doesn't fit. A multi-byte UTF-8 character is never split.
 func arrFromGoString(arr []C.char, src string) {
-	for i, r := range src {
-		if i >= len(arr) {
-			break
+	n := len(src)
+	if n > len(arr) {
+		n = len(arr)
+		for n > 0 && !utf8.RuneStart(src[n]) {
+			n--
 		}
-		arr[i] = C.char(r)
+	}
+	for i := 0; i < n; i++ {
+		arr[i] = C.char(src[i])
 	}
 }
 
-// Set the Broadcast Extension Chunk from WAV (and related) files.
+// Set the Broadcast Extension Chunk from WAV (and related) files. libsndfile appends a line describing its own encoding to the coding history.
 func (f *File) SetBroadcastInfo(bi *BroadcastInfo) (err error) {
-	c := cFromBroadcast(bi)
-	r := C.sf_command(f.s, C.SFC_SET_BROADCAST_INFO, unsafe.Pointer(c), C.int(unsafe.Sizeof(*c)))
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	return f.setBroadcastInfoLocked(bi)
+}
+
+func (f *File) setBroadcastInfoLocked(bi *BroadcastInfo) (err error) {
+	if len(bi.Coding_history) >= BroadcastCodingHistoryMax {
+		return fmt.Errorf("SetBroadcastInfo: coding history is %d bytes, must be less than %d", len(bi.Coding_history), BroadcastCodingHistoryMax)
+	}
+	size := broadcastSize(len(bi.Coding_history) + 1)
+	c := (*C.SF_BROADCAST_INFO)(C.calloc(1, size))
+	defer C.free(unsafe.Pointer(c))
+	if err = fillBroadcast(c, bi); err != nil {
+		return
+	}
+	r := C.sf_command(f.s, C.SFC_SET_BROADCAST_INFO, unsafe.Pointer(c), C.int(size))
 	if r == C.SF_FALSE {
-		err = errors.New(C.GoString(C.sf_strerror(f.s)))
+		err = f.error("SetBroadcastInfo")
 	}
 	return
 }
 
+// A CartTimer is one of the post timers in a CART chunk, marking a sample offset such as the start of the intro or the segue point.
+type CartTimer struct {
+	Usage string // four character code, e.g. "INT1" or "SEG "
+	Value int32  // sample offset of the timer
+}
+
+// CartInfo is the AES46 CART chunk used by broadcast automation systems. Dates are "yyyy-mm-dd" and times are "hh:mm:ss".
+type CartInfo struct {
+	Version            string // four digits, e.g. "0101"
+	Title              string
+	Artist             string
+	CutID              string
+	ClientID           string
+	Category           string
+	Classification     string
+	OutCue             string
+	StartDate          string
+	StartTime          string
+	EndDate            string
+	EndTime            string
+	ProducerAppID      string
+	ProducerAppVersion string
+	UserDef            string
+	LevelReference     int32 // sample value for 0dB reference
+	PostTimers         [8]CartTimer
+	URL                string
+	TagText            string // free form text, up to CartTagTextMax bytes
+}
+
+// CartTagTextMax is the longest CartInfo.TagText that libsndfile will store.
+const CartTagTextMax = 16 * 1024
+
 type LoopMode int
 
 const (
@@ -395,14 +627,41 @@ type LoopInfo struct {
 	Mode    LoopMode
 	Beats   int     // not amount of quarter notes. a full bar of 7/8 is 7 bears
 	Bpm     float32 // Suggestion
-	RootKey int     // MIDI Note
+	RootKey int     // MIDI Note, -1 if there is none (AIFF stores -1 as 0, see SetLoopInfo)
 	Future  [6]int  // nuffink
 }
 
+// validate checks the fields SetLoopInfo can store.
+func (i *LoopInfo) validate() error {
+	if i.TimeSig.Numerator <= 0 {
+		return fmt.Errorf("time signature numerator %d must be positive", i.TimeSig.Numerator)
+	}
+	if d := i.TimeSig.Denominator; d <= 0 || d&(d-1) != 0 {
+		return fmt.Errorf("time signature denominator %d must be a positive power of 2", d)
+	}
+	if i.RootKey < -1 || i.RootKey > 127 {
+		return fmt.Errorf("root key %d must be a MIDI note from 0 to 127, or -1", i.RootKey)
+	}
+	if i.Mode != None && i.Mode != Forward {
+		return fmt.Errorf("loop mode %d can't be stored, use None for a one-shot or Forward for a loop", i.Mode)
+	}
+	if i.Beats < 0 {
+		return fmt.Errorf("beat count %d must not be negative", i.Beats)
+	}
+	if i.Bpm < 0 {
+		return fmt.Errorf("tempo %v must not be negative", i.Bpm)
+	}
+	return nil
+}
+
 //Retrieve loop information for file including time signature, length in beats and original MIDI base note
 
-// Returns populated structure if file contains loop info, otherwise nil. Untested.
+// Returns populated structure if file contains loop info, otherwise nil. AIFF files don't store the tempo, so libsndfile works Bpm out from the length of the file.
 func (f *File) GetLoopInfo() (i *LoopInfo) {
+	if f.lock() != nil {
+		return
+	}
+	defer f.mu.Unlock()
 	c := new(C.SF_LOOP_INFO)
 	r := C.sf_command(f.s, C.SFC_GET_LOOP_INFO, unsafe.Pointer(c), C.int(unsafe.Sizeof(*c)))
 	if r == C.SF_TRUE {
@@ -436,6 +695,10 @@ type Instrument struct {
 
 // Return pointer to populated structure if the file header contains instrument information for the file. nil otherwise.
 func (f *File) GetInstrument() (i *Instrument) {
+	if f.lock() != nil {
+		return
+	}
+	defer f.mu.Unlock()
 	c := new(C.SF_INSTRUMENT)
 	i = new(Instrument)
 	r := C.sf_command(f.s, C.SFC_GET_INSTRUMENT, unsafe.Pointer(c), C.int(unsafe.Sizeof(*c)))
@@ -458,12 +721,27 @@ func (f *File) GetInstrument() (i *Instrument) {
 	return
 }
 
+// A CuePoint is one entry in the cue chunk of a WAV file or a marker in the MARK chunk of an AIFF file. AIFF markers only carry Index, SampleOffset and Name.
+type CuePoint struct {
+	Index        int32  // unique identifier for the cue point
+	Position     uint32 // position in the play order, zero without a playlist
+	FccChunk     int32  // FOURCC of the chunk holding the cue, usually 'data'
+	ChunkStart   int32  // byte offset of that chunk, zero for 'data'
+	BlockStart   int32  // byte offset of the block holding the cue, zero for PCM
+	SampleOffset uint32 // frame the cue point marks
+	Name         string // at most 255 bytes are stored
+}
+
 // This allows libsndfile experts to use the command interface for commands not currently supported. See http://www.mega-nerd.com/libsndfile/command.html
-// The f argument may be nil in cases where the command does not require a SNDFILE argument.
+// The f argument may be nil in cases where the command does not require a SNDFILE argument. If f has been closed, GenericCmd returns 0 without calling sf_command.
 // The method's cmd, data, and datasize arguments are used the same way as the correspondingly named arguments for sf_command
 func GenericCmd(f *File, cmd C.int, data unsafe.Pointer, datasize int) int {
 	var s *C.SNDFILE = nil
 	if f != nil {
+		if f.lock() != nil {
+			return 0
+		}
+		defer f.mu.Unlock()
 		s = f.s
 	}
 	return int(C.sf_command(s, cmd, data, C.int(datasize)))
@@ -500,23 +778,31 @@ const (
 	ChannelMapMax                = C.SF_CHANNEL_MAP_MAX
 )
 
-// Returns a slice full of integers detailing the position of each channel in the file. err will be non-nil on an actual error
+// Returns a slice full of integers detailing the position of each channel in the file. err will be non-nil on an actual error. See also ChannelLayout and SelectChannels.
 func (f *File) GetChannelMapInfo() (channels []int32, err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
 	channels = make([]int32, f.Format.Channels)
-	r := GenericCmd(f, C.SFC_GET_CHANNEL_MAP_INFO, unsafe.Pointer(&channels[0]), len(channels)*4)
+	r := C.sf_command(f.s, C.SFC_GET_CHANNEL_MAP_INFO, unsafe.Pointer(&channels[0]), C.int(len(channels)*4))
 	if r == C.SF_FALSE {
-		err = errors.New(C.GoString(C.sf_strerror(f.s)))
+		err = f.error("GetChannelMapInfo")
 	}
 	return
 }
 
 func (f *File) SetChannelMapInfo(channels []int32) (err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
 	if int32(len(channels)) != f.Format.Channels {
-		err = errors.New("channel map passed in didn't match file channel count " + string(len(channels)) + " != " + string(f.Format.Channels))
+		return fmt.Errorf("channel map passed in didn't match file channel count %d != %d", len(channels), f.Format.Channels)
 	}
-	r := GenericCmd(f, C.SFC_SET_CHANNEL_MAP_INFO, unsafe.Pointer(&channels[0]), len(channels)*4)
+	r := C.sf_command(f.s, C.SFC_SET_CHANNEL_MAP_INFO, unsafe.Pointer(&channels[0]), C.int(len(channels)*4))
 	if r == C.SF_FALSE {
-		err = errors.New(C.GoString(C.sf_strerror(f.s)))
+		err = f.error("SetChannelMapInfo")
 	}
 	return
 }
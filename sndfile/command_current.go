@@ -7,7 +7,13 @@ package sndfile
 // #include <sndfile.h>
 // #include <string.h>
 import "C"
-import "unsafe"
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"unsafe"
+)
 
 func broadcastFromC(c *C.SF_BROADCAST_INFO) *BroadcastInfo {
 	bi := new(BroadcastInfo)
@@ -16,46 +22,54 @@ func broadcastFromC(c *C.SF_BROADCAST_INFO) *BroadcastInfo {
 	bi.Originator_reference = trim(C.GoStringN(&c.originator_reference[0], C.int(len(c.originator_reference[:]))))
 	bi.Origination_date = trim(C.GoStringN(&c.origination_date[0], C.int(len(c.origination_date[:]))))
 	bi.Origination_time = trim(C.GoStringN(&c.origination_time[0], C.int(len(c.origination_time[:]))))
-	bi.Time_reference_low = uint32(c.time_reference_low)
-	bi.Time_reference_high = uint32(c.time_reference_high)
+	bi.TimeReference = uint64(uint32(c.time_reference_high))<<32 | uint64(uint32(c.time_reference_low))
 	bi.Version = uint16(c.version)
 	bi.Umid = trim(C.GoStringN(&c.umid[0], C.int(len(c.umid[:]))))
-	coding_history_bytes := make([]byte, 0, c.coding_history_size)
-	for i, r := range c.coding_history {
-		if i >= int(c.coding_history_size) {
-			break
-		}
-		coding_history_bytes = append(coding_history_bytes, byte(r))
+	bi.LoudnessValue = int16(c.loudness_value)
+	bi.LoudnessRange = int16(c.loudness_range)
+	bi.MaxTruePeakLevel = int16(c.max_true_peak_level)
+	bi.MaxMomentaryLoudness = int16(c.max_momentary_loudness)
+	bi.MaxShortTermLoudness = int16(c.max_shortterm_loudness)
+	size := int(c.coding_history_size)
+	if size > BroadcastCodingHistoryMax {
+		size = BroadcastCodingHistoryMax
 	}
+	// coding_history is declared with 256 bytes but the buffer from broadcastSize holds the whole history
+	bi.Coding_history = trim(C.GoStringN(&c.coding_history[0], C.int(size)))
 	return bi
 }
 
-func cFromBroadcast(bi *BroadcastInfo) (c *C.SF_BROADCAST_INFO) {
-	c = new(C.SF_BROADCAST_INFO)
+// fillBroadcast copies bi into c, which must have been allocated with broadcastSize(len(bi.Coding_history)+1) bytes.
+func fillBroadcast(c *C.SF_BROADCAST_INFO, bi *BroadcastInfo) error {
 	arrFromGoString(c.description[:], bi.Description)
 	arrFromGoString(c.originator[:], bi.Originator)
 	arrFromGoString(c.originator_reference[:], bi.Originator_reference)
 	arrFromGoString(c.origination_date[:], bi.Origination_date)
 	arrFromGoString(c.origination_time[:], bi.Origination_time)
-	c.time_reference_low = C.uint32_t(bi.Time_reference_low)
-	c.time_reference_high = C.uint32_t(bi.Time_reference_high)
+	c.time_reference_low = C.uint32_t(uint32(bi.TimeReference))
+	c.time_reference_high = C.uint32_t(bi.TimeReference >> 32)
 	c.version = C.short(bi.Version)
 	arrFromGoString(c.umid[:], bi.Umid)
-	ch := bi.Coding_history
-	if len(bi.Coding_history) > 256 {
-		ch = bi.Coding_history[0:256]
-	}
-	c.coding_history_size = C.uint32_t(len(ch))
-	for i, r := range ch {
-		c.coding_history[i] = C.char(r)
-	}
-	return c
+	c.loudness_value = C.int16_t(bi.LoudnessValue)
+	c.loudness_range = C.int16_t(bi.LoudnessRange)
+	c.max_true_peak_level = C.int16_t(bi.MaxTruePeakLevel)
+	c.max_momentary_loudness = C.int16_t(bi.MaxMomentaryLoudness)
+	c.max_shortterm_loudness = C.int16_t(bi.MaxShortTermLoudness)
+	n := len(bi.Coding_history)
+	ch := unsafe.Slice(&c.coding_history[0], n)
+	arrFromGoString(ch, bi.Coding_history)
+	c.coding_history_size = C.uint32_t(n)
+	return nil
 }
 
 // Set instrument information from file including MIDI base note, keyboard mapping and looping information (start/stop and mode).
 
 // Return true if the file header contains instrument information for the file. false otherwise.
 func (f *File) SetInstrument(i *Instrument) bool {
+	if f.lock() != nil {
+		return false
+	}
+	defer f.mu.Unlock()
 	c := new(C.SF_INSTRUMENT)
 	c.gain = C.int(i.Gain)
 	c.basenote = C.char(i.Basenote)
@@ -80,3 +94,419 @@ func (f *File) SetInstrument(i *Instrument) bool {
 	r := C.sf_command(f.s, C.SFC_SET_INSTRUMENT, unsafe.Pointer(c), C.int(unsafe.Sizeof(*c)))
 	return (r == C.SF_TRUE)
 }
+
+// cuesSize is the number of bytes needed for an SF_CUES holding count cue points. libsndfile wants at least a full SF_CUES even if there are fewer points.
+func cuesSize(count int) C.size_t {
+	var c C.SF_CUES
+	size := unsafe.Sizeof(c.cue_count) + uintptr(count)*unsafe.Sizeof(c.cue_points[0])
+	if size < unsafe.Sizeof(c) {
+		size = unsafe.Sizeof(c)
+	}
+	return C.size_t(size)
+}
+
+func cuePoints(c *C.SF_CUES, count int) []C.SF_CUE_POINT {
+	return (*[1 << 20]C.SF_CUE_POINT)(unsafe.Pointer(&c.cue_points[0]))[0:count:count]
+}
+
+// Retrieve the cue points (WAV) or markers (AIFF) stored in the file header.
+
+// Returns nil and a nil error if the file has no cue points.
+func (f *File) GetCues() (cues []CuePoint, err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	var count C.uint32_t
+	r := C.sf_command(f.s, C.SFC_GET_CUE_COUNT, unsafe.Pointer(&count), C.int(unsafe.Sizeof(count)))
+	if r == C.SF_FALSE || count == 0 {
+		return
+	}
+	size := cuesSize(int(count))
+	c := (*C.SF_CUES)(C.calloc(1, size))
+	defer C.free(unsafe.Pointer(c))
+	r = C.sf_command(f.s, C.SFC_GET_CUE, unsafe.Pointer(c), C.int(size))
+	if r == C.SF_FALSE {
+		return nil, f.error("GetCues")
+	}
+	n := int(c.cue_count)
+	if n > int(count) {
+		n = int(count)
+	}
+	cues = make([]CuePoint, n)
+	for i, cp := range cuePoints(c, n) {
+		cues[i].Index = int32(cp.indx)
+		cues[i].Position = uint32(cp.position)
+		cues[i].FccChunk = int32(cp.fcc_chunk)
+		cues[i].ChunkStart = int32(cp.chunk_start)
+		cues[i].BlockStart = int32(cp.block_start)
+		cues[i].SampleOffset = uint32(cp.sample_offset)
+		cues[i].Name = trim(C.GoStringN(&cp.name[0], C.int(len(cp.name))))
+	}
+	return
+}
+
+// Set the cue points (WAV) or markers (AIFF) to be written to the file header. This must be called before any audio data is written.
+func (f *File) SetCues(cues []CuePoint) (err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	if f.written {
+		return errors.New("SetCues: must be called before the first write")
+	}
+	size := cuesSize(len(cues))
+	c := (*C.SF_CUES)(C.calloc(1, size))
+	defer C.free(unsafe.Pointer(c))
+	c.cue_count = C.uint32_t(len(cues))
+	points := cuePoints(c, len(cues))
+	for i := range points {
+		cp := &points[i]
+		cp.indx = C.int32_t(cues[i].Index)
+		cp.position = C.uint32_t(cues[i].Position)
+		cp.fcc_chunk = C.int32_t(cues[i].FccChunk)
+		cp.chunk_start = C.int32_t(cues[i].ChunkStart)
+		cp.block_start = C.int32_t(cues[i].BlockStart)
+		cp.sample_offset = C.uint32_t(cues[i].SampleOffset)
+		arrFromGoString(cp.name[:len(cp.name)-1], cues[i].Name)
+	}
+	r := C.sf_command(f.s, C.SFC_SET_CUE, unsafe.Pointer(c), C.int(size))
+	if r == C.SF_FALSE {
+		err = f.error("SetCues")
+	}
+	return
+}
+
+func cartFromC(c *C.SF_CART_INFO) *CartInfo {
+	ci := new(CartInfo)
+	ci.Version = trim(C.GoStringN(&c.version[0], C.int(len(c.version[:]))))
+	ci.Title = trim(C.GoStringN(&c.title[0], C.int(len(c.title[:]))))
+	ci.Artist = trim(C.GoStringN(&c.artist[0], C.int(len(c.artist[:]))))
+	ci.CutID = trim(C.GoStringN(&c.cut_id[0], C.int(len(c.cut_id[:]))))
+	ci.ClientID = trim(C.GoStringN(&c.client_id[0], C.int(len(c.client_id[:]))))
+	ci.Category = trim(C.GoStringN(&c.category[0], C.int(len(c.category[:]))))
+	ci.Classification = trim(C.GoStringN(&c.classification[0], C.int(len(c.classification[:]))))
+	ci.OutCue = trim(C.GoStringN(&c.out_cue[0], C.int(len(c.out_cue[:]))))
+	ci.StartDate = trim(C.GoStringN(&c.start_date[0], C.int(len(c.start_date[:]))))
+	ci.StartTime = trim(C.GoStringN(&c.start_time[0], C.int(len(c.start_time[:]))))
+	ci.EndDate = trim(C.GoStringN(&c.end_date[0], C.int(len(c.end_date[:]))))
+	ci.EndTime = trim(C.GoStringN(&c.end_time[0], C.int(len(c.end_time[:]))))
+	ci.ProducerAppID = trim(C.GoStringN(&c.producer_app_id[0], C.int(len(c.producer_app_id[:]))))
+	ci.ProducerAppVersion = trim(C.GoStringN(&c.producer_app_version[0], C.int(len(c.producer_app_version[:]))))
+	ci.UserDef = trim(C.GoStringN(&c.user_def[0], C.int(len(c.user_def[:]))))
+	ci.LevelReference = int32(c.level_reference)
+	for i, t := range c.post_timers {
+		ci.PostTimers[i].Usage = trim(C.GoStringN(&t.usage_id[0], C.int(len(t.usage_id[:]))))
+		ci.PostTimers[i].Value = int32(t.value)
+	}
+	ci.URL = trim(C.GoStringN(&c.url[0], C.int(len(c.url[:]))))
+	size := int(c.tag_text_size)
+	if size > CartTagTextMax {
+		size = CartTagTextMax
+	}
+	// tag_text is declared with 256 bytes but the buffer from cartSize holds the whole text
+	ci.TagText = trim(C.GoStringN(&c.tag_text[0], C.int(size)))
+	return ci
+}
+
+// cartSize is the size of an SF_CART_INFO with room for tagTextSize bytes of tag text, and never smaller than the fixed SF_CART_INFO.
+func cartSize(tagTextSize int) C.size_t {
+	var c C.SF_CART_INFO
+	size := unsafe.Offsetof(c.tag_text) + uintptr(tagTextSize)
+	if size < unsafe.Sizeof(c) {
+		size = unsafe.Sizeof(c)
+	}
+	return C.size_t(size)
+}
+
+// fillCart copies ci into c, which must have been allocated with cartSize(len(ci.TagText)+1) bytes.
+func fillCart(c *C.SF_CART_INFO, ci *CartInfo) {
+	arrFromGoString(c.version[:], ci.Version)
+	arrFromGoString(c.title[:], ci.Title)
+	arrFromGoString(c.artist[:], ci.Artist)
+	arrFromGoString(c.cut_id[:], ci.CutID)
+	arrFromGoString(c.client_id[:], ci.ClientID)
+	arrFromGoString(c.category[:], ci.Category)
+	arrFromGoString(c.classification[:], ci.Classification)
+	arrFromGoString(c.out_cue[:], ci.OutCue)
+	arrFromGoString(c.start_date[:], ci.StartDate)
+	arrFromGoString(c.start_time[:], ci.StartTime)
+	arrFromGoString(c.end_date[:], ci.EndDate)
+	arrFromGoString(c.end_time[:], ci.EndTime)
+	arrFromGoString(c.producer_app_id[:], ci.ProducerAppID)
+	arrFromGoString(c.producer_app_version[:], ci.ProducerAppVersion)
+	arrFromGoString(c.user_def[:], ci.UserDef)
+	c.level_reference = C.int32_t(ci.LevelReference)
+	for i, t := range ci.PostTimers {
+		arrFromGoString(c.post_timers[i].usage_id[:], t.Usage)
+		c.post_timers[i].value = C.int32_t(t.Value)
+	}
+	arrFromGoString(c.url[:], ci.URL)
+	n := len(ci.TagText)
+	tag := unsafe.Slice(&c.tag_text[0], n)
+	arrFromGoString(tag, ci.TagText)
+	c.tag_text_size = C.uint32_t(n)
+}
+
+// Retrieve the CART chunk from WAV (and related) files.
+func (f *File) GetCartInfo() (ci *CartInfo, ok bool) {
+	if f.lock() != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	size := cartSize(CartTagTextMax)
+	c := (*C.SF_CART_INFO)(C.calloc(1, size))
+	defer C.free(unsafe.Pointer(c))
+
+	r := C.sf_command(f.s, C.SFC_GET_CART_INFO, unsafe.Pointer(c), C.int(size))
+	if r == C.SF_TRUE {
+		ci = cartFromC(c)
+		ok = true
+	}
+	return
+}
+
+// Set the CART chunk for WAV (and related) files. This must be called before any audio data is written.
+func (f *File) SetCartInfo(ci *CartInfo) (err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	if f.written {
+		return errors.New("SetCartInfo: must be called before the first write")
+	}
+	if len(ci.TagText) >= CartTagTextMax {
+		return fmt.Errorf("SetCartInfo: tag text is %d bytes, must be less than %d", len(ci.TagText), CartTagTextMax)
+	}
+	size := cartSize(len(ci.TagText) + 1)
+	c := (*C.SF_CART_INFO)(C.calloc(1, size))
+	defer C.free(unsafe.Pointer(c))
+	fillCart(c, ci)
+	r := C.sf_command(f.s, C.SFC_SET_CART_INFO, unsafe.Pointer(c), C.int(size))
+	if r == C.SF_FALSE {
+		err = f.error("SetCartInfo")
+	}
+	return
+}
+
+// acidChunk encodes i as the little endian "acid" chunk that ACID and most other loop tools write in WAV files.
+func acidChunk(i *LoopInfo) []byte {
+	var flags uint32 = 0x04 // stretch
+	if i.Mode == None {
+		flags |= 0x01 // one-shot
+	}
+	rootKey := uint16(0)
+	if i.RootKey >= 0 {
+		flags |= 0x02 // root note set
+		rootKey = uint16(i.RootKey)
+	}
+	b := new(bytes.Buffer)
+	binary.Write(b, binary.LittleEndian, struct {
+		Flags       uint32
+		RootNote    uint16
+		Unknown1    uint16
+		Unknown2    float32
+		Beats       uint32
+		Denominator uint16
+		Numerator   uint16
+		Tempo       float32
+	}{flags, rootKey, 0x8000, 0, uint32(i.Beats), uint16(i.TimeSig.Denominator), uint16(i.TimeSig.Numerator), i.Bpm})
+	return b.Bytes()
+}
+
+// bascChunk encodes i as the big endian "basc" chunk Apple Loops use in AIFF files. It has no tempo field.
+func bascChunk(i *LoopInfo) []byte {
+	var loopType uint16
+	if i.Mode == None {
+		loopType = 1 // one-shot
+	}
+	rootKey := uint16(0)
+	if i.RootKey >= 0 {
+		rootKey = uint16(i.RootKey)
+	}
+	b := new(bytes.Buffer)
+	binary.Write(b, binary.BigEndian, struct {
+		Version     uint32
+		Beats       uint32
+		RootNote    uint16
+		ScaleType   uint16
+		Numerator   uint16
+		Denominator uint16
+		LoopType    uint16
+		Zero        [66]byte
+	}{1, uint32(i.Beats), rootKey, 3, uint16(i.TimeSig.Numerator), uint16(i.TimeSig.Denominator), loopType, [66]byte{}})
+	return b.Bytes()
+}
+
+// Store loop information (time signature, length in beats, tempo and root note) in the file header so that GetLoopInfo can read it back. WAV files get an ACID chunk and AIFF files an Apple Loops basc chunk; other formats are not supported.
+// This must be called before any audio data is written. Only the None (one-shot) and Forward loop modes can be stored. The basc chunk has no way to mark the root key unset, so in AIFF files a RootKey of -1 is stored as 0 and GetLoopInfo returns 0.
+func (f *File) SetLoopInfo(i *LoopInfo) (err error) {
+	if err = i.validate(); err != nil {
+		return fmt.Errorf("SetLoopInfo: %v", err)
+	}
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	switch f.Format.Format & SF_FORMAT_TYPEMASK {
+	case SF_FORMAT_WAV, SF_FORMAT_WAVEX:
+		return f.setChunkLocked("acid", acidChunk(i))
+	case SF_FORMAT_AIFF:
+		return f.setChunkLocked("basc", bascChunk(i))
+	}
+	return errors.New("SetLoopInfo: loop information can only be written to WAV and AIFF files")
+}
+
+// Bitrate modes for lossy encoders such as MP3 and Opus, used by SetBitrateMode and BitrateMode.
+type BitrateMode int
+
+const (
+	BitrateConstant BitrateMode = C.SF_BITRATE_MODE_CONSTANT
+	BitrateAverage  BitrateMode = C.SF_BITRATE_MODE_AVERAGE
+	BitrateVariable BitrateMode = C.SF_BITRATE_MODE_VARIABLE
+)
+
+func (m BitrateMode) String() string {
+	switch m {
+	case BitrateConstant:
+		return "constant"
+	case BitrateAverage:
+		return "average"
+	case BitrateVariable:
+		return "variable"
+	}
+	return fmt.Sprintf("BitrateMode(%d)", int(m))
+}
+
+// Set the compression level for FLAC, Ogg Vorbis, Opus or MP3 output, from 0.0 (fastest, largest files) to 1.0 (slowest, smallest files). For lossy codecs a higher level also means lower quality.
+// This must be called before any audio data is written.
+func (f *File) SetCompressionLevel(level float64) (err error) {
+	if level < 0 || level > 1 {
+		return fmt.Errorf("SetCompressionLevel: level %v must be between 0 and 1", level)
+	}
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	if f.written {
+		return errors.New("SetCompressionLevel: must be called before the first write")
+	}
+	r := C.sf_command(f.s, C.SFC_SET_COMPRESSION_LEVEL, unsafe.Pointer(&level), C.int(unsafe.Sizeof(level)))
+	if r != C.SF_TRUE {
+		err = f.error("SetCompressionLevel")
+	}
+	return
+}
+
+// Choose between constant, average and variable bitrate encoding for MP3 and Opus output. Needs libsndfile 1.1.0 or later.
+// This must be called before any audio data is written.
+func (f *File) SetBitrateMode(m BitrateMode) (err error) {
+	if m != BitrateConstant && m != BitrateAverage && m != BitrateVariable {
+		return fmt.Errorf("SetBitrateMode: unknown mode %v", m)
+	}
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	if f.written {
+		return errors.New("SetBitrateMode: must be called before the first write")
+	}
+	c := C.int(m)
+	r := C.sf_command(f.s, C.SFC_SET_BITRATE_MODE, unsafe.Pointer(&c), C.int(unsafe.Sizeof(c)))
+	if r != C.SF_TRUE {
+		err = f.error("SetBitrateMode")
+	}
+	return
+}
+
+// Returns the bitrate mode of an MP3 or Opus file, or an error for formats that don't have one. Needs libsndfile 1.1.0 or later.
+func (f *File) BitrateMode() (m BitrateMode, err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	r := C.sf_command(f.s, C.SFC_GET_BITRATE_MODE, nil, 0)
+	m = BitrateMode(r)
+	if m != BitrateConstant && m != BitrateAverage && m != BitrateVariable {
+		return 0, fmt.Errorf("BitrateMode: not available for %s files", f.Format.Format)
+	}
+	return
+}
+
+// Set how much audio, in milliseconds, the Ogg Opus encoder buffers before it writes out a page. Lower latencies give more, smaller pages, which suits streaming; the default is 1000ms.
+// This must be called before any audio data is written.
+func (f *File) SetOggPageLatencyMs(ms float64) (err error) {
+	return f.setOggPageLatency("SetOggPageLatencyMs", C.SFC_SET_OGG_PAGE_LATENCY_MS, ms)
+}
+
+// Like SetOggPageLatencyMs, but the latency is given in Ogg granule positions, which for Opus are samples at 48kHz.
+func (f *File) SetOggPageLatency(samples float64) (err error) {
+	return f.setOggPageLatency("SetOggPageLatency", C.SFC_SET_OGG_PAGE_LATENCY, samples)
+}
+
+func (f *File) setOggPageLatency(op string, cmd C.int, latency float64) (err error) {
+	if latency <= 0 {
+		return fmt.Errorf("%s: latency %v must be positive", op, latency)
+	}
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	if f.written {
+		return fmt.Errorf("%s: must be called before the first write", op)
+	}
+	r := C.sf_command(f.s, cmd, unsafe.Pointer(&latency), C.int(unsafe.Sizeof(latency)))
+	if r != C.SF_TRUE {
+		err = f.error(op)
+	}
+	return
+}
+
+// originalSamplerate returns the recorded original rate, or zero. The caller holds the lock.
+func (f *File) originalSamplerate() int32 {
+	var c C.int
+	if C.sf_command(f.s, C.SFC_GET_ORIGINAL_SAMPLERATE, unsafe.Pointer(&c), C.int(unsafe.Sizeof(c))) != C.SF_TRUE {
+		return 0
+	}
+	return int32(c)
+}
+
+// Returns the sample rate of the audio before it was encoded, as recorded in Opus files. Opus always decodes at 48kHz, so this is the rate to resample to in order to get back what was written. File.Info reports the same rate in OriginalSamplerate.
+func (f *File) GetOriginalSamplerate() (rate int32, err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	if rate = f.originalSamplerate(); rate == 0 {
+		err = fmt.Errorf("GetOriginalSamplerate: not available for %s files", f.Format.Format)
+	}
+	return
+}
+
+// Record the sample rate of the audio before it was encoded. When writing Opus this must be called before any audio data is written. When reading, libsndfile may decode at this rate instead of 48kHz if the codec supports it; check Samplerate with File.Info afterwards.
+func (f *File) SetOriginalSamplerate(rate int32) (err error) {
+	if rate <= 0 {
+		return fmt.Errorf("SetOriginalSamplerate: rate %d must be positive", rate)
+	}
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	if f.written {
+		return errors.New("SetOriginalSamplerate: must be called before the first write")
+	}
+	c := C.int(rate)
+	if C.sf_command(f.s, C.SFC_SET_ORIGINAL_SAMPLERATE, unsafe.Pointer(&c), C.int(unsafe.Sizeof(c))) != C.SF_TRUE {
+		return f.error("SetOriginalSamplerate")
+	}
+	return
+}
+
+// currentFrames asks libsndfile how many frames the file has now. The caller holds the lock.
+func (f *File) currentFrames() (frames int64, ok bool) {
+	var c C.SF_INFO
+	if C.sf_command(f.s, C.SFC_GET_CURRENT_SF_INFO, unsafe.Pointer(&c), C.int(unsafe.Sizeof(c))) != 0 {
+		return
+	}
+	return int64(c.frames), true
+}
@@ -0,0 +1,284 @@
+// +build !legacy
+
+package sndfile
+
+import (
+	"bytes"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+func writeCompressed(t *testing.T, name string, format Format, level float64) int64 {
+	i := Info{Channels: 1, Samplerate: 44100, Format: format}
+	f, err := Open(name, Write, &i)
+	if err != nil {
+		t.Fatal("couldn't open file for write", err)
+	}
+	if err = f.SetCompressionLevel(level); err != nil {
+		t.Fatal("SetCompressionLevel failed", err)
+	}
+	buf := make([]int16, 44100)
+	r := rand.New(rand.NewSource(1))
+	for n := range buf {
+		// quiet noise, so the encoder has something to work at
+		buf[n] = int16(r.Intn(512) - 256)
+	}
+	f.WriteItemsInt16(buf)
+	if f.SetCompressionLevel(level) == nil {
+		t.Error("SetCompressionLevel succeeded after a write")
+	}
+	f.Close()
+	st, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st.Size()
+}
+
+func TestCompressionLevel(t *testing.T) {
+	defer os.Remove("compression.flac")
+	fast := writeCompressed(t, "compression.flac", SF_FORMAT_FLAC|SF_FORMAT_PCM_16, 0)
+	small := writeCompressed(t, "compression.flac", SF_FORMAT_FLAC|SF_FORMAT_PCM_16, 1)
+	if small > fast {
+		t.Errorf("level 1 file is %d bytes, larger than level 0 at %d", small, fast)
+	}
+
+	i := Info{Channels: 1, Samplerate: 44100, Format: SF_FORMAT_FLAC | SF_FORMAT_PCM_16}
+	f, err := Open("compression.flac", Write, &i)
+	if err != nil {
+		t.Fatal("couldn't open file for write", err)
+	}
+	defer f.Close()
+	for _, level := range []float64{-0.1, 1.5} {
+		if f.SetCompressionLevel(level) == nil {
+			t.Errorf("SetCompressionLevel accepted %v", level)
+		}
+	}
+}
+
+func TestBitrateMode(t *testing.T) {
+	i := Info{Channels: 1, Samplerate: 44100, Format: SF_FORMAT_MPEG | SF_FORMAT_MPEG_LAYER_III}
+	if !FormatCheck(i) {
+		t.Skip("MPEG not supported by this libsndfile")
+	}
+	defer os.Remove("bitrate.mp3")
+	f, err := Open("bitrate.mp3", Write, &i)
+	if err != nil {
+		t.Fatal("couldn't open file for write", err)
+	}
+	if err = f.SetBitrateMode(BitrateVariable); err != nil {
+		t.Fatal("SetBitrateMode failed", err)
+	}
+	if m, err := f.BitrateMode(); m != BitrateVariable || err != nil {
+		t.Errorf("expected variable bitrate, got %v %v", m, err)
+	}
+	if f.SetBitrateMode(BitrateMode(3)) == nil {
+		t.Error("SetBitrateMode accepted an unknown mode")
+	}
+	f.WriteItemsInt16(make([]int16, 4410))
+	if f.SetBitrateMode(BitrateConstant) == nil {
+		t.Error("SetBitrateMode succeeded after a write")
+	}
+	f.Close()
+
+	i = Info{Channels: 1, Samplerate: 44100, Format: SF_FORMAT_WAV | SF_FORMAT_PCM_16}
+	f, err = Open("bitrate.wav", Write, &i)
+	if err != nil {
+		t.Fatal("couldn't open file for write", err)
+	}
+	defer os.Remove("bitrate.wav")
+	defer f.Close()
+	if m, err := f.BitrateMode(); err == nil {
+		t.Error("WAV file has bitrate mode", m)
+	}
+}
+
+// memFile is a growable in-memory file for OpenVirtual.
+type memFile struct {
+	b   []byte
+	pos int64
+}
+
+func (m *memFile) virtualIo() VirtualIo {
+	return VirtualIo{
+		GetLength: func(ud interface{}) int64 { return int64(len(m.b)) },
+		Seek: func(offset int64, whence Whence, ud interface{}) int64 {
+			switch whence {
+			case Set:
+				m.pos = offset
+			case Current:
+				m.pos += offset
+			case End:
+				m.pos = int64(len(m.b)) + offset
+			}
+			return m.pos
+		},
+		Read: func(b []byte, ud interface{}) int64 {
+			if m.pos >= int64(len(m.b)) {
+				return 0
+			}
+			n := copy(b, m.b[m.pos:])
+			m.pos += int64(n)
+			return int64(n)
+		},
+		Write: func(b []byte, ud interface{}) int64 {
+			if end := m.pos + int64(len(b)); end > int64(len(m.b)) {
+				m.b = append(m.b, make([]byte, end-int64(len(m.b)))...)
+			}
+			n := copy(m.b[m.pos:], b)
+			m.pos += int64(n)
+			return int64(n)
+		},
+		Tell: func(ud interface{}) int64 { return m.pos },
+	}
+}
+
+func writeOpus(t *testing.T, latencyMs float64) []byte {
+	var m memFile
+	i := Info{Channels: 1, Samplerate: 48000, Format: SF_FORMAT_OGG | SF_FORMAT_OPUS}
+	f, err := OpenVirtual(m.virtualIo(), Write, &i)
+	if err != nil {
+		t.Fatal("couldn't open virtual opus file", err)
+	}
+	if err = f.SetOggPageLatencyMs(latencyMs); err != nil {
+		t.Fatal("SetOggPageLatencyMs failed", err)
+	}
+	buf := make([]float32, 48000*2)
+	r := rand.New(rand.NewSource(1))
+	for n := range buf {
+		buf[n] = r.Float32()*0.5 - 0.25
+	}
+	f.WriteItemsFloat32(buf)
+	if f.SetOggPageLatencyMs(latencyMs) == nil {
+		t.Error("SetOggPageLatencyMs succeeded after a write")
+	}
+	f.Close()
+	return m.b
+}
+
+func TestOggPageLatency(t *testing.T) {
+	if !FormatCheck(Info{Channels: 1, Samplerate: 48000, Format: SF_FORMAT_OGG | SF_FORMAT_OPUS}) {
+		t.Skip("Opus not supported by this libsndfile")
+	}
+	slow := writeOpus(t, 1000)
+	fast := writeOpus(t, 50)
+	slowPages := bytes.Count(slow, []byte("OggS"))
+	fastPages := bytes.Count(fast, []byte("OggS"))
+	if fastPages <= slowPages {
+		t.Errorf("50ms latency gave %d pages, 1000ms gave %d", fastPages, slowPages)
+	}
+	if len(fast)/fastPages >= len(slow)/slowPages {
+		t.Errorf("50ms pages average %d bytes, 1000ms pages %d", len(fast)/fastPages, len(slow)/slowPages)
+	}
+}
+
+func TestOggPageLatencyInvalid(t *testing.T) {
+	i := Info{Channels: 1, Samplerate: 44100, Format: SF_FORMAT_WAV | SF_FORMAT_PCM_16}
+	f, err := Open("latency.wav", Write, &i)
+	if err != nil {
+		t.Fatal("couldn't open file for write", err)
+	}
+	defer os.Remove("latency.wav")
+	defer f.Close()
+	if f.SetOggPageLatencyMs(-1) == nil {
+		t.Error("SetOggPageLatencyMs accepted a negative latency")
+	}
+	if f.SetOggPageLatency(0) == nil {
+		t.Error("SetOggPageLatency accepted zero latency")
+	}
+	if f.SetOggPageLatencyMs(100) == nil {
+		t.Error("SetOggPageLatencyMs succeeded on a WAV file")
+	}
+}
+
+func TestOriginalSamplerate(t *testing.T) {
+	i := Info{Channels: 1, Samplerate: 48000, Format: SF_FORMAT_OGG | SF_FORMAT_OPUS}
+	if !FormatCheck(i) {
+		t.Skip("Opus not supported by this libsndfile")
+	}
+	defer os.Remove("original.opus")
+	f, err := Open("original.opus", Write, &i)
+	if err != nil {
+		t.Fatal("couldn't open file for write", err)
+	}
+	if f.SetOriginalSamplerate(0) == nil {
+		t.Error("SetOriginalSamplerate accepted 0")
+	}
+	if err = f.SetOriginalSamplerate(44100); err != nil {
+		t.Fatal("SetOriginalSamplerate failed", err)
+	}
+	f.WriteItemsFloat32(make([]float32, 4800))
+	if f.SetOriginalSamplerate(22050) == nil {
+		t.Error("SetOriginalSamplerate succeeded after a write")
+	}
+	f.Close()
+
+	var ri Info
+	f, err = Open("original.opus", Read, &ri)
+	if err != nil {
+		t.Fatal("couldn't open file for read", err)
+	}
+	defer f.Close()
+	if r, err := f.GetOriginalSamplerate(); r != 44100 || err != nil {
+		t.Errorf("expected original rate 44100, got %d %v", r, err)
+	}
+	if ri.OriginalSamplerate != 0 {
+		t.Error("Open filled in the original rate", ri.OriginalSamplerate)
+	}
+	if ci, err := f.Info(); ci.OriginalSamplerate != 44100 || ci.Samplerate != 48000 || err != nil {
+		t.Errorf("expected original rate 44100 at 48000 in info, got %d at %d %v", ci.OriginalSamplerate, ci.Samplerate, err)
+	}
+}
+
+func TestNoOriginalSamplerate(t *testing.T) {
+	var i Info
+	f, err := Open("test/ok.aiff", Read, &i)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if r, err := f.GetOriginalSamplerate(); err == nil {
+		t.Error("AIFF file has original rate", r)
+	}
+	if ci, _ := f.Info(); ci.OriginalSamplerate != 0 {
+		t.Error("AIFF file has original rate in info", ci.OriginalSamplerate)
+	}
+}
+
+func TestBroadcastLoudness(t *testing.T) {
+	i := Info{Channels: 1, Samplerate: 8000, Format: SF_FORMAT_WAV | SF_FORMAT_PCM_16}
+	f, err := Open("broadcast", Write, &i)
+	if err != nil {
+		t.Fatal("couldn't open broadcast file for write", err)
+	}
+	bi := BroadcastInfo{
+		Version:              2,
+		LoudnessValue:        -2300,
+		LoudnessRange:        850,
+		MaxTruePeakLevel:     -100,
+		MaxMomentaryLoudness: -1800,
+		MaxShortTermLoudness: -2000,
+	}
+	if err = f.SetBroadcastInfo(&bi); err != nil {
+		t.Fatal("SetBroadcastInfo failed", err)
+	}
+	f.Close()
+
+	f, err = Open("broadcast", Read, &i)
+	if err != nil {
+		t.Fatal("couldn't open broadcast for read", err)
+	}
+	defer f.Close()
+	bi2, ok := f.GetBroadcastInfo()
+	if !ok {
+		t.Fatal("error retrieving broadcast info")
+	}
+	if bi.LoudnessValue != bi2.LoudnessValue ||
+		bi.LoudnessRange != bi2.LoudnessRange ||
+		bi.MaxTruePeakLevel != bi2.MaxTruePeakLevel ||
+		bi.MaxMomentaryLoudness != bi2.MaxMomentaryLoudness ||
+		bi.MaxShortTermLoudness != bi2.MaxShortTermLoudness {
+		t.Errorf("loudness doesn't match\n%v\n%v", bi, *bi2)
+	}
+}
@@ -7,7 +7,10 @@ package sndfile
 // #include <sndfile.h>
 // #include <string.h>
 import "C"
-import "unsafe"
+import (
+	"errors"
+	"unsafe"
+)
 
 func broadcastFromC(c *C.SF_BROADCAST_INFO) *BroadcastInfo {
 	bi := new(BroadcastInfo)
@@ -16,46 +19,47 @@ func broadcastFromC(c *C.SF_BROADCAST_INFO) *BroadcastInfo {
 	bi.Originator_reference = trim(C.GoStringN(&c.originator_reference[0], C.int(len(c.originator_reference[:]))))
 	bi.Origination_date = trim(C.GoStringN(&c.origination_date[0], C.int(len(c.origination_date[:]))))
 	bi.Origination_time = trim(C.GoStringN(&c.origination_time[0], C.int(len(c.origination_time[:]))))
-	bi.Time_reference_low = uint32(uint(c.time_reference_low))
-	bi.Time_reference_high = uint32(uint(c.time_reference_high))
+	bi.TimeReference = uint64(uint32(uint(c.time_reference_high)))<<32 | uint64(uint32(uint(c.time_reference_low)))
 	bi.Version = uint16(c.version)
 	bi.Umid = trim(C.GoStringN(&c.umid[0], C.int(len(c.umid[:]))))
-	coding_history_bytes := make([]byte, 0, c.coding_history_size)
-	for i, r := range c.coding_history {
-		if i >= int(c.coding_history_size) {
-			break
-		}
-		coding_history_bytes = append(coding_history_bytes, byte(r))
+	size := int(c.coding_history_size)
+	if size > BroadcastCodingHistoryMax {
+		size = BroadcastCodingHistoryMax
 	}
+	// coding_history is declared with 256 bytes but the buffer from broadcastSize holds the whole history
+	bi.Coding_history = trim(C.GoStringN(&c.coding_history[0], C.int(size)))
 	return bi
 }
 
-func cFromBroadcast(bi *BroadcastInfo) (c *C.SF_BROADCAST_INFO) {
-	c = new(C.SF_BROADCAST_INFO)
+// fillBroadcast copies bi into c, which must have been allocated with broadcastSize(len(bi.Coding_history)+1) bytes.
+func fillBroadcast(c *C.SF_BROADCAST_INFO, bi *BroadcastInfo) error {
 	arrFromGoString(c.description[:], bi.Description)
 	arrFromGoString(c.originator[:], bi.Originator)
 	arrFromGoString(c.originator_reference[:], bi.Originator_reference)
 	arrFromGoString(c.origination_date[:], bi.Origination_date)
 	arrFromGoString(c.origination_time[:], bi.Origination_time)
-	c.time_reference_low = C.uint(bi.Time_reference_low)
-	c.time_reference_high = C.uint(bi.Time_reference_high)
+	c.time_reference_low = C.uint(uint32(bi.TimeReference))
+	c.time_reference_high = C.uint(bi.TimeReference >> 32)
 	c.version = C.short(bi.Version)
 	arrFromGoString(c.umid[:], bi.Umid)
-	ch := bi.Coding_history
-	if len(bi.Coding_history) > 256 {
-		ch = bi.Coding_history[0:256]
-	}
-	c.coding_history_size = C.uint(len(ch))
-	for i, r := range ch {
-		c.coding_history[i] = C.char(r)
+	if bi.LoudnessValue != 0 || bi.LoudnessRange != 0 || bi.MaxTruePeakLevel != 0 || bi.MaxMomentaryLoudness != 0 || bi.MaxShortTermLoudness != 0 {
+		return errors.New("SetBroadcastInfo: loudness values are not supported by this version of libsndfile")
 	}
-	return c
+	n := len(bi.Coding_history)
+	ch := unsafe.Slice(&c.coding_history[0], n)
+	arrFromGoString(ch, bi.Coding_history)
+	c.coding_history_size = C.uint(n)
+	return nil
 }
 
 // Set instrument information from file including MIDI base note, keyboard mapping and looping information (start/stop and mode).
 
 // Return true if the file header contains instrument information for the file. false otherwise.
 func (f *File) SetInstrument(i *Instrument) bool {
+	if f.lock() != nil {
+		return false
+	}
+	defer f.mu.Unlock()
 	c := new(C.SF_INSTRUMENT)
 	c.gain = C.int(i.Gain)
 	c.basenote = C.char(i.Basenote)
@@ -80,3 +84,128 @@ func (f *File) SetInstrument(i *Instrument) bool {
 	r := C.sf_command(f.s, C.SFC_SET_INSTRUMENT, unsafe.Pointer(c), C.int(unsafe.Sizeof(*c)))
 	return (r == C.SF_TRUE)
 }
+
+// Cue points need libsndfile 1.0.26 or later, so this always fails with the legacy build tag.
+func (f *File) GetCues() (cues []CuePoint, err error) {
+	return nil, errors.New("GetCues: not supported by this version of libsndfile")
+}
+
+// Cue points need libsndfile 1.0.26 or later, so this always fails with the legacy build tag.
+func (f *File) SetCues(cues []CuePoint) (err error) {
+	return errors.New("SetCues: not supported by this version of libsndfile")
+}
+
+// The CART chunk needs libsndfile 1.0.26 or later, so this always fails with the legacy build tag.
+func (f *File) GetCartInfo() (ci *CartInfo, ok bool) {
+	return nil, false
+}
+
+// The CART chunk needs libsndfile 1.0.26 or later, so this always fails with the legacy build tag.
+func (f *File) SetCartInfo(ci *CartInfo) (err error) {
+	return errors.New("SetCartInfo: not supported by this version of libsndfile")
+}
+
+// A Chunk is a raw chunk from the header of a RIFF/WAV, AIFF or related file, such as "iXML", "axml" or "LIST".
+type Chunk struct {
+	ID   string
+	Data []byte
+}
+
+// A ChunkIterator walks the chunks of a file returned by File.Chunks.
+type ChunkIterator struct {
+	err error
+}
+
+// The chunk API needs libsndfile 1.0.26 or later, so with the legacy build tag the iterator never returns a chunk and Err reports that.
+func (f *File) Chunks(id string) *ChunkIterator {
+	return &ChunkIterator{errors.New("Chunks: not supported by this version of libsndfile")}
+}
+
+// Next always returns false with the legacy build tag.
+func (it *ChunkIterator) Next() bool {
+	return false
+}
+
+// Chunk returns the chunk read by the last successful call to Next.
+func (it *ChunkIterator) Chunk() Chunk {
+	return Chunk{}
+}
+
+// Err returns the error, if any, that stopped iteration.
+func (it *ChunkIterator) Err() error {
+	return it.err
+}
+
+// The chunk API needs libsndfile 1.0.26 or later, so this always fails with the legacy build tag.
+func (f *File) SetChunk(id string, data []byte) (err error) {
+	return errors.New("SetChunk: not supported by this version of libsndfile")
+}
+
+// Writing loop information needs the chunk API from libsndfile 1.0.26 or later, so this always fails with the legacy build tag.
+func (f *File) SetLoopInfo(i *LoopInfo) (err error) {
+	return errors.New("SetLoopInfo: not supported by this version of libsndfile")
+}
+
+// Compression levels need libsndfile 1.0.29 or later, so this always fails with the legacy build tag.
+func (f *File) SetCompressionLevel(level float64) (err error) {
+	return errors.New("SetCompressionLevel: not supported by this version of libsndfile")
+}
+
+// Bitrate modes for lossy encoders. libsndfile only has them from 1.1.0, so with the legacy build tag no modes are defined.
+type BitrateMode int
+
+// Bitrate modes need libsndfile 1.1.0 or later, so this always fails with the legacy build tag.
+func (f *File) SetBitrateMode(m BitrateMode) (err error) {
+	return errors.New("SetBitrateMode: not supported by this version of libsndfile")
+}
+
+// Bitrate modes need libsndfile 1.1.0 or later, so this always fails with the legacy build tag.
+func (f *File) BitrateMode() (m BitrateMode, err error) {
+	return 0, errors.New("BitrateMode: not supported by this version of libsndfile")
+}
+
+// Ogg page latency needs libsndfile 1.0.29 or later, so this always fails with the legacy build tag.
+func (f *File) SetOggPageLatencyMs(ms float64) (err error) {
+	return errors.New("SetOggPageLatencyMs: not supported by this version of libsndfile")
+}
+
+// Ogg page latency needs libsndfile 1.0.29 or later, so this always fails with the legacy build tag.
+func (f *File) SetOggPageLatency(samples float64) (err error) {
+	return errors.New("SetOggPageLatency: not supported by this version of libsndfile")
+}
+
+// Original sample rates need libsndfile 1.0.29 or later, so Info leaves OriginalSamplerate at zero with the legacy build tag.
+func (f *File) originalSamplerate() int32 {
+	return 0
+}
+
+// Original sample rates need libsndfile 1.0.29 or later, so this always fails with the legacy build tag.
+func (f *File) GetOriginalSamplerate() (rate int32, err error) {
+	return 0, errors.New("GetOriginalSamplerate: not supported by this version of libsndfile")
+}
+
+// Original sample rates need libsndfile 1.0.29 or later, so this always fails with the legacy build tag.
+func (f *File) SetOriginalSamplerate(rate int32) (err error) {
+	return errors.New("SetOriginalSamplerate: not supported by this version of libsndfile")
+}
+
+// Older versions of libsndfile can't report the current SF_INFO. A file open for reading has the length from its header, or more if the read position is past it; plain SEEK_CUR gives the read position. For writing, the write position is moved to the end to find the length and then put back. The caller holds the lock.
+func (f *File) currentFrames() (frames int64, ok bool) {
+	if f.mode == Read {
+		frames = f.Format.Frames
+		if pos := int64(C.sf_seek(f.s, 0, C.SEEK_CUR)); pos > frames {
+			frames = pos
+		}
+		return frames, true
+	}
+	cur := C.sf_seek(f.s, 0, C.SEEK_CUR|C.SFM_WRITE)
+	if cur < 0 {
+		return
+	}
+	end := C.sf_seek(f.s, 0, C.SEEK_END|C.SFM_WRITE)
+	C.sf_seek(f.s, cur, C.SEEK_SET|C.SFM_WRITE)
+	if end < 0 {
+		return
+	}
+	return int64(end), true
+}
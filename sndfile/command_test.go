@@ -123,6 +123,71 @@ func TestFormats(t *testing.T) {
 	}
 }
 
+func TestFormatLists(t *testing.T) {
+	majors := MajorFormats()
+	if len(majors) != GetMajorFormatCount() {
+		t.Errorf("expected %d major formats, got %d", GetMajorFormatCount(), len(majors))
+	}
+	found := false
+	for _, m := range majors {
+		if m.Format&^SF_FORMAT_TYPEMASK != 0 || m.Name == "" || m.Extension == "" {
+			t.Errorf("bad major format %+v", m)
+		}
+		if m.Format == SF_FORMAT_WAV {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("WAV missing from major formats")
+	}
+
+	subs := SubFormats()
+	if len(subs) != GetSubFormatCount() {
+		t.Errorf("expected %d subtypes, got %d", GetSubFormatCount(), len(subs))
+	}
+	for _, s := range subs {
+		if s.Format&^SF_FORMAT_SUBMASK != 0 || s.Name == "" {
+			t.Errorf("bad subtype %+v", s)
+		}
+	}
+
+	simple := SimpleFormats()
+	if len(simple) != GetSimpleFormatCount() {
+		t.Errorf("expected %d simple formats, got %d", GetSimpleFormatCount(), len(simple))
+	}
+	for _, s := range simple {
+		i := Info{Channels: 1, Samplerate: 44100, Format: s.Format}
+		if !FormatCheck(i) {
+			t.Errorf("simple format %+v fails FormatCheck", s)
+		}
+	}
+}
+
+func TestSupportedSubtypes(t *testing.T) {
+	has := func(l []FormatInfo, f Format) bool {
+		for _, fi := range l {
+			if fi.Format == f {
+				return true
+			}
+		}
+		return false
+	}
+	wav := SupportedSubtypes(SF_FORMAT_WAV | SF_FORMAT_PCM_16)
+	if !has(wav, SF_FORMAT_PCM_16) || !has(wav, SF_FORMAT_FLOAT) || !has(wav, SF_FORMAT_ULAW) {
+		t.Error("WAV subtypes missing PCM_16, FLOAT or ULAW", wav)
+	}
+	if has(wav, SF_FORMAT_VORBIS) || has(wav, SF_FORMAT_PCM_S8) {
+		t.Error("WAV subtypes include VORBIS or PCM_S8", wav)
+	}
+	flac := SupportedSubtypes(SF_FORMAT_FLAC)
+	if !has(flac, SF_FORMAT_PCM_16) || has(flac, SF_FORMAT_FLOAT) {
+		t.Error("unexpected FLAC subtypes", flac)
+	}
+	if l := SupportedSubtypes(SF_FORMAT_PCM_16); len(l) != 0 {
+		t.Error("subtypes found for no major format", l)
+	}
+}
+
 func isLittleEndian() bool {
 	var i int32 = 0x01020304
 	u := unsafe.Pointer(&i)
@@ -133,7 +198,7 @@ func isLittleEndian() bool {
 
 func TestRawSwap(t *testing.T) {
 	// set up file to be checked
-	i := &Info{0, 44100, 1, SF_FORMAT_WAV | SF_FORMAT_PCM_16, 0, 0}
+	i := &Info{0, 44100, 1, SF_FORMAT_WAV | SF_FORMAT_PCM_16, 0, 0, 0}
 	f, err := Open("leout.wav", Write, i)
 	if err != nil {
 		t.Fatalf("couldn't open file for writing: %v", err)
@@ -518,6 +583,9 @@ func TestUpdateHeader(t *testing.T) {
 			t.Error("header didn't update?", l, "!=", (i+1)*len(out)*2)
 		}
 		totsize += len(out) * 2
+		if f.Format.Frames != int64(totsize/2) {
+			t.Error("Frames didn't update", f.Format.Frames, "!=", totsize/2)
+		}
 	}
 	b = f.SetUpdateHeaderAuto(false)
 	if b {
@@ -530,6 +598,10 @@ func TestUpdateHeader(t *testing.T) {
 			t.Error("header updated when auto = false", l, "!=", nl)
 		}
 		totsize += len(out) * 2
+		// Frames follows the writes even while the header doesn't
+		if f.Format.Frames != int64(totsize/2) {
+			t.Error("Frames didn't update", f.Format.Frames, "!=", totsize/2)
+		}
 	}
 	f.UpdateHeaderNow()
 	nl := checkLength(t)
@@ -557,12 +629,15 @@ func TestBroadcast(t *testing.T) {
 	bi.Originator_reference = "http://hydrogenproject.com"
 	bi.Origination_date = "2011/09/27"
 	bi.Origination_time = "17:49"
-	bi.Time_reference_low = 123456
-	bi.Time_reference_high = 7891011
-	bi.Version = 1 // libsndfile always writes a 1
+	bi.TimeReference = 7891011<<32 | 123456
+	bi.Version = 2
 	bi.Umid = "ummm"
-	bi.Coding_history = ""
-	f.SetBroadcastInfo(&bi)
+	// longer than the 256 bytes in the fixed size struct
+	bi.Coding_history = strings.Repeat("A=ANALOGUE,M=stereo,T=Studer A816\r\n", 10)
+	err = f.SetBroadcastInfo(&bi)
+	if err != nil {
+		t.Fatal("SetBroadcastInfo failed", err)
+	}
 	f.Close()
 
 	f, err = Open("broadcast", Read, &i)
@@ -571,15 +646,130 @@ func TestBroadcast(t *testing.T) {
 	}
 	bi2, ok := f.GetBroadcastInfo()
 	if !ok {
-		t.Error("error retrieving broadcast info", err)
+		t.Fatal("error retrieving broadcast info", err)
 	}
 	if bi.Description != bi2.Description {
 		t.Error("desc doesn't match \"" + bi.Description + "\" \"" + bi2.Description + "\"")
 	}
+	if bi.TimeReference != bi2.TimeReference {
+		t.Errorf("time reference doesn't match %d %d", bi.TimeReference, bi2.TimeReference)
+	}
+	if !strings.HasPrefix(bi2.Coding_history, bi.Coding_history) {
+		t.Fatal("coding history was truncated: ", bi2.Coding_history)
+	}
 	expected_coding_history := regexp.MustCompile("A=PCM,F=8000,W=16,M=mono,T=libsndfile-.*")
-	if expected_coding_history.MatchString(bi.Coding_history) {
+	if !expected_coding_history.MatchString(bi2.Coding_history[len(bi.Coding_history):]) {
 		t.Error("coding history mismatch: ", bi2.Coding_history, " != ", expected_coding_history)
 	}
+	f.Close()
+}
+
+func TestCart(t *testing.T) {
+	var i Info
+	i.Format = SF_FORMAT_WAV | SF_FORMAT_PCM_16
+	i.Channels = 1
+	i.Samplerate = 8000
+
+	f, err := Open("cart.wav", Write, &i)
+	if err != nil {
+		t.Fatal("couldn't open cart file for write", err)
+	}
+
+	var ci CartInfo
+	ci.Version = "0101"
+	ci.Title = "gosndfile test spot"
+	ci.Artist = "republic of nynex"
+	ci.CutID = "CUT0042"
+	ci.StartDate = "2011-09-27"
+	ci.StartTime = "17:49:00"
+	ci.EndDate = "2099-12-31"
+	ci.EndTime = "23:59:59"
+	ci.LevelReference = 32768
+	ci.PostTimers[0] = CartTimer{"INT1", 8000}
+	ci.PostTimers[1] = CartTimer{"SEG ", 16000}
+	ci.URL = "http://hydrogenproject.com"
+	// longer than the 256 bytes in the fixed size struct
+	ci.TagText = strings.Repeat("tag text ", 100)
+	err = f.SetCartInfo(&ci)
+	if err != nil {
+		t.Fatal("SetCartInfo failed", err)
+	}
+	f.WriteItems(make([]int16, 100))
+	if f.SetCartInfo(&ci) == nil {
+		t.Error("SetCartInfo succeeded after a write")
+	}
+	f.Close()
+
+	f, err = Open("cart.wav", Read, &i)
+	if err != nil {
+		t.Fatal("couldn't open cart file for read", err)
+	}
+	ci2, ok := f.GetCartInfo()
+	if !ok {
+		t.Fatal("error retrieving cart info")
+	}
+	if !reflect.DeepEqual(ci, *ci2) {
+		t.Errorf("cart info doesn't match\n%v\n%v", ci, *ci2)
+	}
+	f.Close()
+
+	ci.TagText = strings.Repeat("x", CartTagTextMax)
+	f, err = Open("cart.wav", Write, &i)
+	if err != nil {
+		t.Fatal("couldn't open cart file for write", err)
+	}
+	if f.SetCartInfo(&ci) == nil {
+		t.Error("oversized tag text should be rejected")
+	}
+	f.Close()
+}
+
+// strings are copied byte for byte, and cut short without splitting a character
+func TestMultiByteStrings(t *testing.T) {
+	var i Info
+	i.Format = SF_FORMAT_WAV | SF_FORMAT_PCM_16
+	i.Channels = 1
+	i.Samplerate = 8000
+
+	f, err := Open("cart.wav", Write, &i)
+	if err != nil {
+		t.Fatal("couldn't open cart file for write", err)
+	}
+	var ci CartInfo
+	ci.Version = "0101"
+	ci.Title = "Øresund – Ægir ♪"
+	// 63 ASCII bytes then a 3 byte character straddling the end of the 64 byte artist field
+	ci.Artist = strings.Repeat("a", 63) + "♪"
+	ci.TagText = "Grüße aus Köln, 東京"
+	if err = f.SetCartInfo(&ci); err != nil {
+		t.Fatal("SetCartInfo failed", err)
+	}
+	cues := []CuePoint{{Index: 1, SampleOffset: 10, Name: "café crème"}}
+	if err = f.SetCues(cues); err != nil {
+		t.Fatal("SetCues failed", err)
+	}
+	f.WriteItems(make([]int16, 100))
+	f.Close()
+
+	f, err = Open("cart.wav", Read, &i)
+	if err != nil {
+		t.Fatal("couldn't open cart file for read", err)
+	}
+	defer f.Close()
+	ci2, ok := f.GetCartInfo()
+	if !ok {
+		t.Fatal("error retrieving cart info")
+	}
+	if ci2.Title != ci.Title || ci2.TagText != ci.TagText {
+		t.Errorf("cart strings read back as %q and %q", ci2.Title, ci2.TagText)
+	}
+	if ci2.Artist != strings.Repeat("a", 63) {
+		t.Errorf("long artist read back as %q", ci2.Artist)
+	}
+	got, err := f.GetCues()
+	if err != nil || len(got) != 1 || got[0].Name != cues[0].Name {
+		t.Errorf("cues read back as %v: %v", got, err)
+	}
 }
 
 func TestInstrument(t *testing.T) {
@@ -742,8 +932,198 @@ func TestAmbisonic(t *testing.T) {
 
 }
 
+func testCues(t *testing.T, name string, format Format) {
+	var i Info
+	i.Format = format
+	i.Channels = 1
+	i.Samplerate = 8000
+	os.Remove(name)
+	f, err := Open(name, Write, &i)
+	if err != nil {
+		t.Fatal("couldn't open file for write", err)
+	}
+	cues := []CuePoint{
+		{Index: 1, SampleOffset: 10, Name: "verse"},
+		{Index: 2, SampleOffset: 500, Name: "chorus"},
+		{Index: 3, SampleOffset: 900, Name: "outro"},
+	}
+	if err = f.SetCues(cues); err != nil {
+		t.Fatal("SetCues failed", err)
+	}
+	_, err = f.WriteItems(make([]int16, 1000))
+	if err != nil {
+		t.Fatal("couldn't write", err)
+	}
+	if err = f.SetCues(cues[:1]); err == nil {
+		t.Error("SetCues succeeded after writing")
+	}
+	f.Close()
+
+	f, err = Open(name, Read, &i)
+	if err != nil {
+		t.Fatal("couldn't open file for read", err)
+	}
+	got, err := f.GetCues()
+	if err != nil {
+		t.Fatal("GetCues failed", err)
+	}
+	if len(got) != len(cues) {
+		t.Fatalf("got %d cues, expected %d: %v", len(got), len(cues), got)
+	}
+	for j := range cues {
+		if got[j].Index != cues[j].Index || got[j].SampleOffset != cues[j].SampleOffset || got[j].Name != cues[j].Name {
+			t.Errorf("cue %d was %v, expected %v", j, got[j], cues[j])
+		}
+	}
+	f.Close()
+}
+
+func TestCuesWav(t *testing.T) {
+	testCues(t, "cues.wav", SF_FORMAT_WAV|SF_FORMAT_PCM_16)
+}
+
+func TestCuesAiff(t *testing.T) {
+	testCues(t, "cues.aiff", SF_FORMAT_AIFF|SF_FORMAT_PCM_16)
+}
+
+func TestNoCues(t *testing.T) {
+	var i Info
+	i.Format = SF_FORMAT_WAV | SF_FORMAT_PCM_16
+	i.Channels = 1
+	i.Samplerate = 8000
+	f, err := Open("cues.wav", Write, &i)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteItems(make([]int16, 100))
+	f.Close()
+	f, err = Open("cues.wav", Read, &i)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cues, err := f.GetCues()
+	if cues != nil || err != nil {
+		t.Error("expected no cues", cues, err)
+	}
+	f.Close()
+}
+
 // how do i make sure vbr quality is passed along correctly?
 
 // i need to create a file with loop info. AIFF only?
 
 // embedded file. buh?
+
+func testLoopInfo(t *testing.T, name string, format Format, frames int, li LoopInfo) {
+	li2 := writeLoopInfo(t, name, format, frames, li)
+	if li2.TimeSig != li.TimeSig || li2.Mode != li.Mode || li2.Beats != li.Beats || li2.RootKey != li.RootKey {
+		t.Errorf("loop info doesn't match\n%v\n%v", li, *li2)
+	}
+	if math.Abs(float64(li2.Bpm-li.Bpm)) > 0.01 {
+		t.Errorf("tempo doesn't match: expected %v got %v", li.Bpm, li2.Bpm)
+	}
+}
+
+// writeLoopInfo writes a file with loop information li and returns what GetLoopInfo reads back from it
+func writeLoopInfo(t *testing.T, name string, format Format, frames int, li LoopInfo) *LoopInfo {
+	var i Info
+	i.Format = format
+	i.Channels = 1
+	i.Samplerate = 44100
+
+	f, err := Open(name, Write, &i)
+	if err != nil {
+		t.Fatal("couldn't open loop file for write", err)
+	}
+	err = f.SetLoopInfo(&li)
+	if err != nil {
+		t.Fatal("SetLoopInfo failed", err)
+	}
+	f.WriteItems(make([]int16, frames))
+	f.Close()
+
+	f, err = Open(name, Read, &i)
+	if err != nil {
+		t.Fatal("couldn't open loop file for read", err)
+	}
+	defer f.Close()
+	li2 := f.GetLoopInfo()
+	if li2 == nil {
+		t.Fatal("no loop info in", name)
+	}
+	return li2
+}
+
+func TestLoopInfoWav(t *testing.T) {
+	var li LoopInfo
+	li.TimeSig.Numerator = 7
+	li.TimeSig.Denominator = 8
+	li.Mode = Forward
+	li.Beats = 14
+	li.Bpm = 96.5
+	li.RootKey = 60
+	testLoopInfo(t, "loop.wav", SF_FORMAT_WAV|SF_FORMAT_PCM_16, 1000, li)
+
+	li.Mode = None
+	testLoopInfo(t, "loop.wav", SF_FORMAT_WAV|SF_FORMAT_PCM_16, 1000, li)
+}
+
+func TestLoopInfoAiff(t *testing.T) {
+	// AIFF has nowhere to keep the tempo, so it comes back as 4 beats in 2 seconds
+	var li LoopInfo
+	li.TimeSig.Numerator = 4
+	li.TimeSig.Denominator = 4
+	li.Mode = Forward
+	li.Beats = 4
+	li.Bpm = 120
+	li.RootKey = 48
+	testLoopInfo(t, "loop.aiff", SF_FORMAT_AIFF|SF_FORMAT_PCM_16, 88200, li)
+
+	// basc can't mark the root key unset, so -1 comes back as 0
+	li.RootKey = -1
+	if li2 := writeLoopInfo(t, "loop.aiff", SF_FORMAT_AIFF|SF_FORMAT_PCM_16, 88200, li); li2.RootKey != 0 {
+		t.Errorf("unset root key read back from AIFF as %d", li2.RootKey)
+	}
+}
+
+func TestSetLoopInfoInvalid(t *testing.T) {
+	var i Info
+	i.Format = SF_FORMAT_WAV | SF_FORMAT_PCM_16
+	i.Channels = 1
+	i.Samplerate = 44100
+
+	f, err := Open("loop.wav", Write, &i)
+	if err != nil {
+		t.Fatal("couldn't open loop file for write", err)
+	}
+	var li LoopInfo
+	li.TimeSig.Numerator = 4
+	li.TimeSig.Denominator = 3
+	li.Mode = Forward
+	li.RootKey = -1
+	if f.SetLoopInfo(&li) == nil {
+		t.Error("SetLoopInfo accepted a denominator of 3")
+	}
+	li.TimeSig.Denominator = 4
+	li.RootKey = 200
+	if f.SetLoopInfo(&li) == nil {
+		t.Error("SetLoopInfo accepted a root key of 200")
+	}
+	li.RootKey = -1
+	li.Mode = Backward
+	if f.SetLoopInfo(&li) == nil {
+		t.Error("SetLoopInfo accepted a backward loop")
+	}
+	f.Close()
+
+	i.Format = SF_FORMAT_FLAC | SF_FORMAT_PCM_16
+	f, err = Open("loop.flac", Write, &i)
+	if err != nil {
+		t.Fatal("couldn't open flac file for write", err)
+	}
+	li.Mode = Forward
+	if f.SetLoopInfo(&li) == nil {
+		t.Error("SetLoopInfo accepted a FLAC file")
+	}
+	f.Close()
+}
@@ -0,0 +1,323 @@
+// Package convert turns packed PCM bytes, as read and written by File.ReadRaw and File.WriteRaw, into Go sample slices and back. It doesn't need libsndfile.
+//
+// Integer samples are left justified the same way libsndfile does it: a 24 bit sample 0x123456 is the int32 0x12345600 and the int16 0x1234. Floating point samples run from -1 to just under 1. Narrowing integer conversions truncate; conversions from floating point round to the nearest sample and clip.
+package convert
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"unsafe"
+)
+
+// Layout describes how one sample is packed.
+type Layout struct {
+	Bytes    int              // 1, 2, 3 or 4
+	Unsigned bool             // offset binary, as in SF_FORMAT_PCM_U8, rather than two's complement
+	Order    binary.ByteOrder // binary.LittleEndian or binary.BigEndian, ignored for 1 byte samples
+}
+
+// Common layouts.
+var (
+	S8    = Layout{1, false, binary.LittleEndian}
+	U8    = Layout{1, true, binary.LittleEndian}
+	S16LE = Layout{2, false, binary.LittleEndian}
+	S16BE = Layout{2, false, binary.BigEndian}
+	S24LE = Layout{3, false, binary.LittleEndian}
+	S24BE = Layout{3, false, binary.BigEndian}
+	S32LE = Layout{4, false, binary.LittleEndian}
+	S32BE = Layout{4, false, binary.BigEndian}
+)
+
+// NativeOrder returns the byte order of the CPU.
+func NativeOrder() binary.ByteOrder {
+	var x uint16 = 1
+	b := (*[2]byte)(unsafe.Pointer(&x))
+	if b[0] == 1 {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}
+
+// RawLayout returns the layout of the raw data in a file with samples of the given size, where needsSwap is the result of File.RawNeedsEndianSwap: libsndfile reports whether raw data differs from the CPU's byte order, not which order it is in.
+func RawLayout(bytes int, unsigned, needsSwap bool) Layout {
+	order := NativeOrder()
+	if needsSwap {
+		if order == binary.LittleEndian {
+			order = binary.BigEndian
+		} else {
+			order = binary.LittleEndian
+		}
+	}
+	return Layout{bytes, unsigned, order}
+}
+
+func (l Layout) String() string {
+	s := "S"
+	if l.Unsigned {
+		s = "U"
+	}
+	s += fmt.Sprint(l.Bytes * 8)
+	if l.Bytes > 1 {
+		if l.bigEndian() {
+			s += "BE"
+		} else {
+			s += "LE"
+		}
+	}
+	return s
+}
+
+func (l Layout) bigEndian() bool {
+	return l.Order == binary.BigEndian
+}
+
+// Validate reports whether l can be used, returning an error describing the problem if not. Check layouts built from file metadata with it; the Decode and Encode methods panic when given an invalid Layout.
+func (l Layout) Validate() error {
+	if l.Bytes < 1 || l.Bytes > 4 {
+		return fmt.Errorf("convert: %d byte samples are not supported", l.Bytes)
+	}
+	if l.Bytes > 1 && l.Order != binary.LittleEndian && l.Order != binary.BigEndian {
+		return errors.New("convert: Layout.Order must be binary.LittleEndian or binary.BigEndian")
+	}
+	return nil
+}
+
+func (l Layout) check() {
+	if err := l.Validate(); err != nil {
+		panic(err)
+	}
+}
+
+// Len returns the number of whole samples in b. Like the Decode and Encode methods it panics if l is invalid.
+func (l Layout) Len(b []byte) int {
+	l.check()
+	return len(b) / l.Bytes
+}
+
+// bias flips the sign bit of a left justified sample, converting between offset binary and two's complement.
+func (l Layout) bias() uint32 {
+	if l.Unsigned {
+		return 1 << 31
+	}
+	return 0
+}
+
+// DecodeInt32 unpacks samples from src into dst and returns how many it converted, which is the smaller of len(dst) and l.Len(src).
+func (l Layout) DecodeInt32(dst []int32, src []byte) int {
+	n := l.Len(src)
+	if len(dst) < n {
+		n = len(dst)
+	}
+	bias := l.bias()
+	switch {
+	case l.Bytes == 1:
+		for i := 0; i < n; i++ {
+			dst[i] = int32(uint32(src[i])<<24 ^ bias)
+		}
+	case l.Bytes == 2 && l.bigEndian():
+		for i := 0; i < n; i++ {
+			b := src[2*i : 2*i+2]
+			dst[i] = int32((uint32(b[0])<<24 | uint32(b[1])<<16) ^ bias)
+		}
+	case l.Bytes == 2:
+		for i := 0; i < n; i++ {
+			b := src[2*i : 2*i+2]
+			dst[i] = int32((uint32(b[1])<<24 | uint32(b[0])<<16) ^ bias)
+		}
+	case l.Bytes == 3 && l.bigEndian():
+		for i := 0; i < n; i++ {
+			b := src[3*i : 3*i+3]
+			dst[i] = int32((uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8) ^ bias)
+		}
+	case l.Bytes == 3:
+		for i := 0; i < n; i++ {
+			b := src[3*i : 3*i+3]
+			dst[i] = int32((uint32(b[2])<<24 | uint32(b[1])<<16 | uint32(b[0])<<8) ^ bias)
+		}
+	case l.Bytes == 4 && l.bigEndian():
+		for i := 0; i < n; i++ {
+			dst[i] = int32(binary.BigEndian.Uint32(src[4*i:]) ^ bias)
+		}
+	default:
+		for i := 0; i < n; i++ {
+			dst[i] = int32(binary.LittleEndian.Uint32(src[4*i:]) ^ bias)
+		}
+	}
+	return n
+}
+
+// EncodeInt32 packs samples from src into dst and returns how many it converted, which is the smaller of len(src) and l.Len(dst). Bits below the layout's resolution are dropped.
+func (l Layout) EncodeInt32(dst []byte, src []int32) int {
+	n := l.Len(dst)
+	if len(src) < n {
+		n = len(src)
+	}
+	bias := l.bias()
+	switch {
+	case l.Bytes == 1:
+		for i := 0; i < n; i++ {
+			dst[i] = byte((uint32(src[i]) ^ bias) >> 24)
+		}
+	case l.Bytes == 2 && l.bigEndian():
+		for i := 0; i < n; i++ {
+			v := uint32(src[i]) ^ bias
+			dst[2*i], dst[2*i+1] = byte(v>>24), byte(v>>16)
+		}
+	case l.Bytes == 2:
+		for i := 0; i < n; i++ {
+			v := uint32(src[i]) ^ bias
+			dst[2*i], dst[2*i+1] = byte(v>>16), byte(v>>24)
+		}
+	case l.Bytes == 3 && l.bigEndian():
+		for i := 0; i < n; i++ {
+			v := uint32(src[i]) ^ bias
+			dst[3*i], dst[3*i+1], dst[3*i+2] = byte(v>>24), byte(v>>16), byte(v>>8)
+		}
+	case l.Bytes == 3:
+		for i := 0; i < n; i++ {
+			v := uint32(src[i]) ^ bias
+			dst[3*i], dst[3*i+1], dst[3*i+2] = byte(v>>8), byte(v>>16), byte(v>>24)
+		}
+	case l.Bytes == 4 && l.bigEndian():
+		for i := 0; i < n; i++ {
+			binary.BigEndian.PutUint32(dst[4*i:], uint32(src[i])^bias)
+		}
+	default:
+		for i := 0; i < n; i++ {
+			binary.LittleEndian.PutUint32(dst[4*i:], uint32(src[i])^bias)
+		}
+	}
+	return n
+}
+
+// The other sample types go through a small int32 buffer, so they share the packing loops above.
+const chunk = 256
+
+// DecodeInt16 is DecodeInt32 for int16 samples.
+func (l Layout) DecodeInt16(dst []int16, src []byte) int {
+	var buf [chunk]int32
+	n := 0
+	for n < len(dst) {
+		m := l.DecodeInt32(buf[:minInt(chunk, len(dst)-n)], src[n*l.Bytes:])
+		for i, v := range buf[:m] {
+			dst[n+i] = int16(v >> 16)
+		}
+		n += m
+		if m < chunk {
+			break
+		}
+	}
+	return n
+}
+
+// EncodeInt16 is EncodeInt32 for int16 samples.
+func (l Layout) EncodeInt16(dst []byte, src []int16) int {
+	var buf [chunk]int32
+	n := 0
+	for n < len(src) {
+		m := minInt(chunk, len(src)-n)
+		for i, v := range src[n : n+m] {
+			buf[i] = int32(v) << 16
+		}
+		m = l.EncodeInt32(dst[n*l.Bytes:], buf[:m])
+		n += m
+		if m < chunk {
+			break
+		}
+	}
+	return n
+}
+
+// DecodeFloat32 is DecodeInt32 for float32 samples.
+func (l Layout) DecodeFloat32(dst []float32, src []byte) int {
+	var buf [chunk]int32
+	n := 0
+	for n < len(dst) {
+		m := l.DecodeInt32(buf[:minInt(chunk, len(dst)-n)], src[n*l.Bytes:])
+		for i, v := range buf[:m] {
+			dst[n+i] = float32(float64(v) / (1 << 31))
+		}
+		n += m
+		if m < chunk {
+			break
+		}
+	}
+	return n
+}
+
+// EncodeFloat32 is EncodeInt32 for float32 samples.
+func (l Layout) EncodeFloat32(dst []byte, src []float32) int {
+	var buf [chunk]int32
+	n := 0
+	for n < len(src) {
+		m := minInt(chunk, len(src)-n)
+		for i, v := range src[n : n+m] {
+			buf[i] = l.quantize(float64(v))
+		}
+		m = l.EncodeInt32(dst[n*l.Bytes:], buf[:m])
+		n += m
+		if m < chunk {
+			break
+		}
+	}
+	return n
+}
+
+// DecodeFloat64 is DecodeInt32 for float64 samples.
+func (l Layout) DecodeFloat64(dst []float64, src []byte) int {
+	var buf [chunk]int32
+	n := 0
+	for n < len(dst) {
+		m := l.DecodeInt32(buf[:minInt(chunk, len(dst)-n)], src[n*l.Bytes:])
+		for i, v := range buf[:m] {
+			dst[n+i] = float64(v) / (1 << 31)
+		}
+		n += m
+		if m < chunk {
+			break
+		}
+	}
+	return n
+}
+
+// EncodeFloat64 is EncodeInt32 for float64 samples.
+func (l Layout) EncodeFloat64(dst []byte, src []float64) int {
+	var buf [chunk]int32
+	n := 0
+	for n < len(src) {
+		m := minInt(chunk, len(src)-n)
+		for i, v := range src[n : n+m] {
+			buf[i] = l.quantize(v)
+		}
+		m = l.EncodeInt32(dst[n*l.Bytes:], buf[:m])
+		n += m
+		if m < chunk {
+			break
+		}
+	}
+	return n
+}
+
+// quantize rounds x to the nearest sample at the layout's resolution, clipping it to the range the layout can hold, and left justifies it.
+func (l Layout) quantize(x float64) int32 {
+	bits := uint(l.Bytes * 8)
+	full := float64(int64(1) << (bits - 1))
+	v := math.Floor(x*full + 0.5)
+	if v >= full {
+		v = full - 1
+	} else if v < -full {
+		v = -full
+	} else if v != v { // NaN
+		v = 0
+	}
+	return int32(v) << (32 - bits)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
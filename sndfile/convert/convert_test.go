@@ -0,0 +1,219 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+	"unsafe"
+)
+
+var allLayouts = []Layout{
+	S8, U8, S16LE, S16BE, S24LE, S24BE, S32LE, S32BE,
+	{2, true, binary.LittleEndian}, {2, true, binary.BigEndian},
+	{3, true, binary.LittleEndian}, {3, true, binary.BigEndian},
+	{4, true, binary.LittleEndian}, {4, true, binary.BigEndian},
+}
+
+func TestKnownBytes(t *testing.T) {
+	tests := []struct {
+		l Layout
+		b []byte
+		v int32
+	}{
+		{S8, []byte{0x81}, -0x7f000000},
+		{U8, []byte{0x81}, 0x01000000},
+		{U8, []byte{0x00}, math.MinInt32},
+		{S16LE, []byte{0x34, 0x12}, 0x12340000},
+		{S16BE, []byte{0x12, 0x34}, 0x12340000},
+		{S24LE, []byte{0x56, 0x34, 0x12}, 0x12345600},
+		{S24BE, []byte{0x12, 0x34, 0x56}, 0x12345600},
+		{S24LE, []byte{0x00, 0x00, 0x80}, math.MinInt32},
+		{S32LE, []byte{0x78, 0x56, 0x34, 0x12}, 0x12345678},
+		{S32BE, []byte{0xff, 0xff, 0xff, 0xfe}, -2},
+		{Layout{2, true, binary.BigEndian}, []byte{0x80, 0x01}, 0x00010000},
+	}
+	for _, test := range tests {
+		v := make([]int32, 1)
+		if n := test.l.DecodeInt32(v, test.b); n != 1 || v[0] != test.v {
+			t.Errorf("%v % x: decoded %d samples, %#x, expected %#x", test.l, test.b, n, v[0], test.v)
+		}
+		b := make([]byte, test.l.Bytes)
+		if n := test.l.EncodeInt32(b, []int32{test.v}); n != 1 || !bytes.Equal(b, test.b) {
+			t.Errorf("%v %#x: encoded %d samples, % x, expected % x", test.l, test.v, n, b, test.b)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	// more than one chunk, and not a multiple of it
+	const n = 3*chunk + 17
+	for _, l := range allLayouts {
+		shift := uint(32 - 8*l.Bytes)
+		in := make([]int32, n)
+		for i := range in {
+			in[i] = int32(uint32(i)*2654435761) >> shift << shift
+		}
+		b := make([]byte, n*l.Bytes)
+		if c := l.EncodeInt32(b, in); c != n {
+			t.Fatalf("%v: encoded %d of %d", l, c, n)
+		}
+		out := make([]int32, n)
+		if c := l.DecodeInt32(out, b); c != n || !reflect.DeepEqual(in, out) {
+			t.Errorf("%v: int32 doesn't round trip, %d samples", l, c)
+		}
+
+		f64 := make([]float64, n)
+		if c := l.DecodeFloat64(f64, b); c != n {
+			t.Fatalf("%v: decoded %d of %d float64", l, c, n)
+		}
+		b2 := make([]byte, len(b))
+		if c := l.EncodeFloat64(b2, f64); c != n || !bytes.Equal(b, b2) {
+			t.Errorf("%v: float64 doesn't round trip, %d samples", l, c)
+		}
+
+		if l.Bytes <= 3 {
+			// float32 has 24 bits of mantissa
+			f32 := make([]float32, n)
+			l.DecodeFloat32(f32, b)
+			b2 = make([]byte, len(b))
+			if c := l.EncodeFloat32(b2, f32); c != n || !bytes.Equal(b, b2) {
+				t.Errorf("%v: float32 doesn't round trip, %d samples", l, c)
+			}
+		}
+
+		if l.Bytes <= 2 {
+			i16 := make([]int16, n)
+			if c := l.DecodeInt16(i16, b); c != n {
+				t.Fatalf("%v: decoded %d of %d int16", l, c, n)
+			}
+			for i, v := range i16 {
+				if int32(v) != in[i]>>16 {
+					t.Fatalf("%v: int16 sample %d is %#x, expected %#x", l, i, v, in[i]>>16)
+				}
+			}
+			b2 = make([]byte, len(b))
+			if c := l.EncodeInt16(b2, i16); c != n || !bytes.Equal(b, b2) {
+				t.Errorf("%v: int16 doesn't round trip, %d samples", l, c)
+			}
+		}
+	}
+}
+
+func TestShortBuffers(t *testing.T) {
+	b := make([]byte, 10*3+2)
+	if n := S24LE.Len(b); n != 10 {
+		t.Errorf("Len is %d, expected 10", n)
+	}
+	if n := S24LE.DecodeInt16(make([]int16, 20), b); n != 10 {
+		t.Errorf("decoded %d samples from 10 and a bit", n)
+	}
+	if n := S24LE.DecodeFloat32(make([]float32, 4), b); n != 4 {
+		t.Errorf("decoded %d samples into room for 4", n)
+	}
+	if n := S24LE.EncodeFloat64(b, make([]float64, 20)); n != 10 {
+		t.Errorf("encoded %d samples into room for 10", n)
+	}
+	if n := S16BE.EncodeInt16(make([]byte, 2*chunk), make([]int16, 2*chunk+1)); n != chunk {
+		t.Errorf("encoded %d samples into room for %d", n, chunk)
+	}
+}
+
+func TestQuantize(t *testing.T) {
+	tests := []struct {
+		x float64
+		v int16
+	}{
+		{0, 0},
+		{0.5, 0x4000},
+		{-1, -0x8000},
+		{1, 0x7fff},
+		{2, 0x7fff},
+		{-2, -0x8000},
+		{math.NaN(), 0},
+		{1.4 / 32768, 1},
+		{1.6 / 32768, 2},
+		{-1.6 / 32768, -2},
+	}
+	for _, test := range tests {
+		b := make([]byte, 2)
+		S16LE.EncodeFloat64(b, []float64{test.x})
+		if v := int16(binary.LittleEndian.Uint16(b)); v != test.v {
+			t.Errorf("%v encoded as %#x, expected %#x", test.x, v, test.v)
+		}
+	}
+	b := []byte{0}
+	U8.EncodeFloat32(b, []float32{0})
+	if b[0] != 0x80 {
+		t.Errorf("unsigned silence is %#x", b[0])
+	}
+}
+
+func TestRawLayout(t *testing.T) {
+	native := NativeOrder()
+	if l := RawLayout(2, false, false); l.Order != native || l.Bytes != 2 || l.Unsigned {
+		t.Errorf("unswapped raw layout is %v", l)
+	}
+	l := RawLayout(3, false, true)
+	if l.Order == native {
+		t.Errorf("swapped raw layout is %v", l)
+	}
+	var x uint32 = 0x01020304
+	b := make([]byte, 4)
+	native.PutUint32(b, x)
+	if *(*uint32)(unsafe.Pointer(&b[0])) != x {
+		t.Error("NativeOrder is wrong")
+	}
+	if s := S24BE.String(); s != "S24BE" {
+		t.Errorf("S24BE prints as %q", s)
+	}
+	if s := U8.String(); s != "U8" {
+		t.Errorf("U8 prints as %q", s)
+	}
+}
+
+func TestInvalidLayout(t *testing.T) {
+	for _, l := range allLayouts {
+		if err := l.Validate(); err != nil {
+			t.Errorf("%v: %v", l, err)
+		}
+	}
+	bad := []Layout{
+		{0, false, binary.LittleEndian},
+		{5, false, binary.LittleEndian},
+		{2, false, nil},
+	}
+	for _, l := range bad {
+		if l.Validate() == nil {
+			t.Errorf("%d byte layout with order %v validated", l.Bytes, l.Order)
+		}
+	}
+	if err := (Layout{1, true, nil}).Validate(); err != nil {
+		t.Error("1 byte layout needs no byte order:", err)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("5 byte layout didn't panic")
+		}
+	}()
+	Layout{5, false, binary.LittleEndian}.DecodeInt32(make([]int32, 1), make([]byte, 5))
+}
+
+func BenchmarkDecodeS24LEFloat32(b *testing.B) {
+	src := make([]byte, 3*4096)
+	dst := make([]float32, 4096)
+	b.SetBytes(int64(len(src)))
+	for i := 0; i < b.N; i++ {
+		S24LE.DecodeFloat32(dst, src)
+	}
+}
+
+func BenchmarkEncodeS24LEFloat32(b *testing.B) {
+	src := make([]float32, 4096)
+	dst := make([]byte, 3*4096)
+	b.SetBytes(int64(len(dst)))
+	for i := 0; i < b.N; i++ {
+		S24LE.EncodeFloat32(dst, src)
+	}
+}
@@ -0,0 +1,197 @@
+package sndfile
+
+// #cgo pkg-config: sndfile
+// #include <sndfile.h>
+import "C"
+
+import (
+	"math"
+	"math/rand"
+	"unsafe"
+)
+
+// Dither algorithms for SetDitherOnWrite and SetDitherOnRead.
+type DitherType int
+
+const (
+	DitherNone       DitherType = C.SFD_NO_DITHER      // plain rounding
+	DitherWhite      DitherType = C.SFD_WHITE          // rectangular noise of 1 LSB peak to peak
+	DitherTriangular DitherType = C.SFD_TRIANGULAR_PDF // triangular (TPDF) noise of 2 LSB peak to peak
+
+	// DitherNoiseShaped is triangular dither with first order error feedback, which moves the noise towards high frequencies where it is harder to hear. libsndfile has no such type, so this package applies it in Go and gives libsndfile DitherTriangular; the value is outside libsndfile's range.
+	DitherNoiseShaped DitherType = -1
+)
+
+// DitherInfo selects a dither algorithm and its level, as a multiple of the usual amplitude for the algorithm. A zero Level means 1. Name is filled in by DitherTypes and ignored otherwise.
+type DitherInfo struct {
+	Type  DitherType
+	Level float64
+	Name  string
+}
+
+func (d DitherInfo) toC() (c C.SF_DITHER_INFO) {
+	c._type = C.int(d.Type)
+	if d.Type == DitherNoiseShaped {
+		c._type = C.SFD_TRIANGULAR_PDF
+	}
+	if d.Level != 0 {
+		c._type |= C.SFD_CUSTOM_LEVEL
+		c.level = C.double(d.Level)
+	}
+	return
+}
+
+// The dither types this package can apply itself, for when libsndfile won't list its own.
+var builtinDitherTypes = []DitherInfo{
+	{Type: DitherNone, Name: "none"},
+	{Type: DitherWhite, Name: "white"},
+	{Type: DitherTriangular, Name: "triangular"},
+	{Type: DitherNoiseShaped, Name: "noise shaped"},
+}
+
+// Returns the dither algorithms libsndfile knows about, followed by DitherNoiseShaped, which only this package implements.
+// libsndfile is asked without a file, which not every version answers; when it reports none, the types SetDitherOnWrite can apply in Go are returned instead.
+func DitherTypes() (l []DitherInfo) {
+	count := int(C.sf_command(nil, C.SFC_GET_DITHER_INFO_COUNT, nil, 0))
+	for i := 0; i < count; i++ {
+		var c C.SF_DITHER_INFO
+		c._type = C.int(i)
+		if C.sf_command(nil, C.SFC_GET_DITHER_INFO, unsafe.Pointer(&c), C.int(unsafe.Sizeof(c))) != 0 {
+			continue
+		}
+		d := DitherInfo{Type: DitherType(c._type &^ C.SFD_CUSTOM_LEVEL), Level: float64(c.level)}
+		if c.name != nil {
+			d.Name = C.GoString(c.name)
+		}
+		l = append(l, d)
+	}
+	if len(l) == 0 {
+		return append(l, builtinDitherTypes...)
+	}
+	return append(l, DitherInfo{Type: DitherNoiseShaped, Name: "noise shaped"})
+}
+
+// Set the dither applied when floating point data is written to a file with a lower resolution integer format. Use DitherNone to turn it off again.
+// The dither in libsndfile itself doesn't do anything yet, so for 8, 16 and 24 bit PCM files this package dithers float32 and float64 writes in Go before passing them on, with noise shaping if d.Type is DitherNoiseShaped. This only happens while float normalization is on, which is the default.
+func (f *File) SetDitherOnWrite(d DitherInfo) (err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	c := d.toC()
+	if r := C.sf_command(f.s, C.SFC_SET_DITHER_ON_WRITE, unsafe.Pointer(&c), C.int(unsafe.Sizeof(c))); r != 0 {
+		return f.codeError("SetDitherOnWrite", r)
+	}
+	f.dither = newDitherState(d, f.Format.Format, int(f.Format.Channels))
+	return
+}
+
+// Set the dither applied when data is read from a file into a lower resolution integer type. libsndfile accepts the setting but doesn't dither yet.
+func (f *File) SetDitherOnRead(d DitherInfo) (err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	c := d.toC()
+	if r := C.sf_command(f.s, C.SFC_SET_DITHER_ON_READ, unsafe.Pointer(&c), C.int(unsafe.Sizeof(c))); r != 0 {
+		return f.codeError("SetDitherOnRead", r)
+	}
+	return
+}
+
+// ditherState is the Go side write dither for one file.
+type ditherState struct {
+	typ   DitherType
+	level float64
+	scale float64 // largest sample in the file's format, which libsndfile maps to 1.0
+	shift uint    // from the file's sample size up to 32 bits
+	rng   *rand.Rand
+	errs  []float64 // last quantization error in each channel, for DitherNoiseShaped
+}
+
+// newDitherState returns nil if there is nothing to do for d and format.
+func newDitherState(d DitherInfo, format Format, channels int) *ditherState {
+	if d.Type != DitherWhite && d.Type != DitherTriangular && d.Type != DitherNoiseShaped {
+		return nil
+	}
+	var bits uint
+	switch format.Subtype() {
+	case SF_FORMAT_PCM_S8, SF_FORMAT_PCM_U8:
+		bits = 8
+	case SF_FORMAT_PCM_16:
+		bits = 16
+	case SF_FORMAT_PCM_24:
+		bits = 24
+	default:
+		return nil
+	}
+	level := d.Level
+	if level == 0 {
+		level = 1
+	}
+	return &ditherState{
+		typ:   d.Type,
+		level: level,
+		scale: float64(int64(1)<<(bits-1) - 1),
+		shift: 32 - bits,
+		rng:   rand.New(rand.NewSource(1)),
+		errs:  make([]float64, channels),
+	}
+}
+
+// sample dithers and quantizes x, in the range -1 to 1, to the file's resolution, and returns it scaled up to a full 32 bit sample for sf_write_int. ch is the channel x belongs to.
+func (d *ditherState) sample(ch int, x float64) C.int {
+	v := x * d.scale
+	if d.typ == DitherNoiseShaped {
+		// feeding back the last error makes the total error its first difference, which has little low frequency energy
+		v -= d.errs[ch]
+	}
+	q := v
+	switch d.typ {
+	case DitherWhite:
+		q += (d.rng.Float64() - 0.5) * d.level
+	case DitherTriangular, DitherNoiseShaped:
+		q += (d.rng.Float64() - d.rng.Float64()) * d.level
+	}
+	q = math.Floor(q + 0.5)
+	// the error is taken before clipping so it stays within a few LSB
+	d.errs[ch] = q - v
+	if q < -d.scale-1 {
+		q = -d.scale - 1
+	} else if q > d.scale {
+		q = d.scale
+	}
+	return C.int(int32(q) << d.shift)
+}
+
+// ditherActive reports whether float writes should go through the Go dither. normCmd is SFC_GET_NORM_FLOAT or SFC_GET_NORM_DOUBLE. The caller holds the lock.
+func (f *File) ditherActive(normCmd C.int) bool {
+	return f.dither != nil && C.sf_command(f.s, normCmd, nil, 0) == C.SF_TRUE
+}
+
+// writeDithered writes the dithered samples in buf as ints, either as items or as frames.
+func (f *File) writeDithered(op string, buf []C.int, frames bool) (written int64, err error) {
+	if frames {
+		n := len(buf) / int(f.Format.Channels)
+		return f.writeResult(op, C.sf_writef_int(f.s, &buf[0], C.sf_count_t(n)), n)
+	}
+	return f.writeResult(op, C.sf_write_int(f.s, &buf[0], C.sf_count_t(len(buf))), len(buf))
+}
+
+func (f *File) writeDitheredFloat32(op string, in []float32, frames bool) (int64, error) {
+	buf := make([]C.int, len(in))
+	channels := int(f.Format.Channels)
+	for i, x := range in {
+		buf[i] = f.dither.sample(i%channels, float64(x))
+	}
+	return f.writeDithered(op, buf, frames)
+}
+
+func (f *File) writeDitheredFloat64(op string, in []float64, frames bool) (int64, error) {
+	buf := make([]C.int, len(in))
+	channels := int(f.Format.Channels)
+	for i, x := range in {
+		buf[i] = f.dither.sample(i%channels, x)
+	}
+	return f.writeDithered(op, buf, frames)
+}
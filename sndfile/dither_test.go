@@ -0,0 +1,169 @@
+package sndfile
+
+import (
+	"math"
+	"os"
+	"testing"
+)
+
+// writeDitherTest writes n copies of x to a 16 bit file with the given dither and returns what was stored.
+func writeDitherTest(t *testing.T, d DitherInfo, format Format, x float64, n int) []int16 {
+	defer os.Remove("dither.wav")
+	i := Info{Channels: 1, Samplerate: 44100, Format: format}
+	f, err := Open("dither.wav", Write, &i)
+	if err != nil {
+		t.Fatal("couldn't open file for write", err)
+	}
+	if err = f.SetDitherOnWrite(d); err != nil {
+		t.Fatal("SetDitherOnWrite failed", err)
+	}
+	in := make([]float64, n)
+	for k := range in {
+		in[k] = x
+	}
+	if w, err := f.WriteFramesFloat64(in); w != int64(n) || err != nil {
+		t.Fatalf("only wrote %d of %d frames: %v", w, n, err)
+	}
+	f.Close()
+
+	f, err = Open("dither.wav", Read, &i)
+	if err != nil {
+		t.Fatal("couldn't open file for read", err)
+	}
+	defer f.Close()
+	out := make([]int16, n)
+	if r, err := f.ReadFramesInt16(out); r != int64(n) || err != nil {
+		t.Fatalf("only read %d of %d frames: %v", r, n, err)
+	}
+	return out
+}
+
+func TestDitherOnWrite(t *testing.T) {
+	const n = 20000
+	x := 1000.3 / 32768
+
+	plain := writeDitherTest(t, DitherInfo{Type: DitherNone}, SF_FORMAT_WAV|SF_FORMAT_PCM_16, x, n)
+	for k, v := range plain {
+		if v != 1000 {
+			t.Fatalf("undithered sample %d is %d, expected 1000", k, v)
+		}
+	}
+
+	for _, typ := range []DitherType{DitherWhite, DitherTriangular} {
+		out := writeDitherTest(t, DitherInfo{Type: typ}, SF_FORMAT_WAV|SF_FORMAT_PCM_16, x, n)
+		var sum float64
+		var changed int
+		for k, v := range out {
+			if v != plain[k] {
+				changed++
+			}
+			if v < 999 || v > 1002 {
+				t.Fatalf("dither %d: sample %d is %d, too far from 1000.3", typ, k, v)
+			}
+			sum += float64(v)
+		}
+		// the point of dither: the average keeps the part below 1 LSB
+		if mean := sum / n; math.Abs(mean-1000.3) > 0.05 {
+			t.Errorf("dither %d: mean %v, expected 1000.3", typ, mean)
+		}
+		// without dither every sample rounds to 1000; with it a good share of them don't
+		if changed < n/10 {
+			t.Errorf("dither %d changed only %d of %d samples", typ, changed, n)
+		}
+	}
+
+	silence := writeDitherTest(t, DitherInfo{Type: DitherTriangular, Level: 2}, SF_FORMAT_WAV|SF_FORMAT_PCM_16, 0, n)
+	var nonzero int
+	for _, v := range silence {
+		if v < -2 || v > 2 {
+			t.Fatalf("level 2 dither on silence gave %d", v)
+		}
+		if v != 0 {
+			nonzero++
+		}
+	}
+	if nonzero == 0 {
+		t.Error("dithered silence is still silent")
+	}
+}
+
+func TestDitherFloatFile(t *testing.T) {
+	// nothing to dither when the file is floating point too
+	out := writeDitherTest(t, DitherInfo{Type: DitherTriangular}, SF_FORMAT_WAV|SF_FORMAT_FLOAT, 1000.0/32768, 100)
+	for k, v := range out {
+		if v != 1000 {
+			t.Fatalf("sample %d is %d, expected 1000", k, v)
+		}
+	}
+}
+
+func TestDitherOnRead(t *testing.T) {
+	var i Info
+	f, err := Open("test/ok.aiff", Read, &i)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err = f.SetDitherOnRead(DitherInfo{Type: DitherTriangular}); err != nil {
+		t.Error("SetDitherOnRead failed", err)
+	}
+}
+
+func TestDitherTypes(t *testing.T) {
+	have := make(map[DitherType]bool)
+	for _, d := range DitherTypes() {
+		have[d.Type] = true
+	}
+	for _, typ := range []DitherType{DitherNone, DitherWhite, DitherTriangular, DitherNoiseShaped} {
+		if !have[typ] {
+			t.Errorf("dither type %d missing from %v", typ, DitherTypes())
+		}
+	}
+}
+
+// lowRatio compares the energy of the error summed over 8 samples, a crude low pass filter, with 8 times the energy of the error. It is about 1 for white noise and about 1/8 for first order noise shaping, where the sum of the errors telescopes.
+func lowRatio(d *ditherState, x float64, n int) float64 {
+	var last [8]float64
+	var low, all float64
+	for k := 0; k < n; k++ {
+		e := float64(int32(d.sample(0, x))>>d.shift) - x*d.scale
+		last[k%8] = e
+		all += e * e
+		if k >= 8 {
+			var sum float64
+			for _, v := range last {
+				sum += v
+			}
+			low += sum * sum
+		}
+	}
+	return low / (8 * all)
+}
+
+func TestDitherNoiseShaped(t *testing.T) {
+	const n = 20000
+	x := 1000.3 / 32768
+	tpdf := newDitherState(DitherInfo{Type: DitherTriangular}, SF_FORMAT_WAV|SF_FORMAT_PCM_16, 1)
+	shaped := newDitherState(DitherInfo{Type: DitherNoiseShaped}, SF_FORMAT_WAV|SF_FORMAT_PCM_16, 1)
+	if r := lowRatio(tpdf, x, n); r < 0.7 {
+		t.Errorf("triangular dither error isn't white, low frequency ratio %v", r)
+	}
+	if r := lowRatio(shaped, x, n); r > 0.3 {
+		t.Errorf("noise shaped dither error has too much low frequency energy, ratio %v", r)
+	}
+
+	// the shaped error still averages out
+	var sum float64
+	for k := 0; k < n; k++ {
+		sum += float64(int32(shaped.sample(0, x)) >> shaped.shift)
+	}
+	if mean := sum / n; math.Abs(mean-1000.3) > 0.05 {
+		t.Errorf("noise shaped dither: mean %v, expected 1000.3", mean)
+	}
+
+	// each channel has its own error feedback
+	stereo := newDitherState(DitherInfo{Type: DitherNoiseShaped}, SF_FORMAT_WAV|SF_FORMAT_PCM_16, 2)
+	if len(stereo.errs) != 2 {
+		t.Errorf("expected error state for 2 channels, have %d", len(stereo.errs))
+	}
+}
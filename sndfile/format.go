@@ -0,0 +1,266 @@
+package sndfile
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Names used by Format.String and ParseFormat, taken from the constant names in sndfile.h.
+var majorNames = map[Format]string{
+	SF_FORMAT_WAV:   "WAV",
+	SF_FORMAT_AIFF:  "AIFF",
+	SF_FORMAT_AU:    "AU",
+	SF_FORMAT_RAW:   "RAW",
+	SF_FORMAT_PAF:   "PAF",
+	SF_FORMAT_SVX:   "SVX",
+	SF_FORMAT_NIST:  "NIST",
+	SF_FORMAT_VOC:   "VOC",
+	SF_FORMAT_IRCAM: "IRCAM",
+	SF_FORMAT_W64:   "W64",
+	SF_FORMAT_MAT4:  "MAT4",
+	SF_FORMAT_MAT5:  "MAT5",
+	SF_FORMAT_PVF:   "PVF",
+	SF_FORMAT_XI:    "XI",
+	SF_FORMAT_HTK:   "HTK",
+	SF_FORMAT_SDS:   "SDS",
+	SF_FORMAT_AVR:   "AVR",
+	SF_FORMAT_WAVEX: "WAVEX",
+	SF_FORMAT_SD2:   "SD2",
+	SF_FORMAT_FLAC:  "FLAC",
+	SF_FORMAT_CAF:   "CAF",
+	SF_FORMAT_WVE:   "WVE",
+	SF_FORMAT_OGG:   "OGG",
+	SF_FORMAT_MPC2K: "MPC2K",
+	SF_FORMAT_RF64:  "RF64",
+}
+
+var subtypeNames = map[Format]string{
+	SF_FORMAT_PCM_S8:    "PCM_S8",
+	SF_FORMAT_PCM_16:    "PCM_16",
+	SF_FORMAT_PCM_24:    "PCM_24",
+	SF_FORMAT_PCM_32:    "PCM_32",
+	SF_FORMAT_PCM_U8:    "PCM_U8",
+	SF_FORMAT_FLOAT:     "FLOAT",
+	SF_FORMAT_DOUBLE:    "DOUBLE",
+	SF_FORMAT_ULAW:      "ULAW",
+	SF_FORMAT_ALAW:      "ALAW",
+	SF_FORMAT_IMA_ADPCM: "IMA_ADPCM",
+	SF_FORMAT_MS_ADPCM:  "MS_ADPCM",
+	SF_FORMAT_GSM610:    "GSM610",
+	SF_FORMAT_VOX_ADPCM: "VOX_ADPCM",
+	SF_FORMAT_G721_32:   "G721_32",
+	SF_FORMAT_G723_24:   "G723_24",
+	SF_FORMAT_G723_40:   "G723_40",
+	SF_FORMAT_DWVW_12:   "DWVW_12",
+	SF_FORMAT_DWVW_16:   "DWVW_16",
+	SF_FORMAT_DWVW_24:   "DWVW_24",
+	SF_FORMAT_DWVW_N:    "DWVW_N",
+	SF_FORMAT_DPCM_8:    "DPCM_8",
+	SF_FORMAT_DPCM_16:   "DPCM_16",
+	SF_FORMAT_VORBIS:    "VORBIS",
+}
+
+var endianNames = map[Format]string{
+	SF_ENDIAN_FILE:   "file",
+	SF_ENDIAN_LITTLE: "little",
+	SF_ENDIAN_BIG:    "big",
+	SF_ENDIAN_CPU:    "cpu",
+}
+
+// Major returns the major format (container) part of f, for example SF_FORMAT_WAV.
+func (f Format) Major() Format {
+	return f & SF_FORMAT_TYPEMASK
+}
+
+// Subtype returns the subtype (encoding) part of f, for example SF_FORMAT_PCM_16.
+func (f Format) Subtype() Format {
+	return f & SF_FORMAT_SUBMASK
+}
+
+// Endian returns the endian-ness part of f, one of the SF_ENDIAN_* constants.
+func (f Format) Endian() Format {
+	return f & SF_FORMAT_ENDMASK
+}
+
+// String describes f as major/subtype/endian, for example "WAV/PCM_24/little". Parts that are zero are left out, so a format using the file's default endian-ness prints as "WAV/PCM_24". Parts with no known name are printed in hex.
+// The result for any known format can be passed to ParseFormat.
+func (f Format) String() string {
+	var parts []string
+	if m := f.Major(); m != 0 {
+		parts = append(parts, formatName(majorNames, m))
+	}
+	if s := f.Subtype(); s != 0 {
+		parts = append(parts, formatName(subtypeNames, s))
+	}
+	if e := f.Endian(); e != 0 {
+		parts = append(parts, endianNames[e])
+	}
+	if other := f &^ (SF_FORMAT_TYPEMASK | SF_FORMAT_SUBMASK | SF_FORMAT_ENDMASK); other != 0 {
+		parts = append(parts, fmt.Sprintf("%#08x", int32(other)))
+	}
+	if len(parts) == 0 {
+		return "0"
+	}
+	return strings.Join(parts, "/")
+}
+
+func formatName(names map[Format]string, f Format) string {
+	if n, ok := names[f]; ok {
+		return n
+	}
+	return fmt.Sprintf("%#08x", int32(f))
+}
+
+// ParseFormat is the inverse of Format.String. It takes a major format, subtype and endian-ness separated by slashes, for example "flac/pcm_16" or "wav/float/big", and returns the Format with those parts set. Names are not case sensitive and any of the parts may be left out, but each can only appear once.
+func ParseFormat(s string) (f Format, err error) {
+	if strings.TrimSpace(s) == "" {
+		return 0, fmt.Errorf("ParseFormat: empty format")
+	}
+	var seen Format
+	for _, part := range strings.Split(s, "/") {
+		name := strings.TrimSpace(part)
+		var v, mask Format
+		var ok bool
+		if v, ok = lookupFormat(majorNames, strings.ToUpper(name)); ok {
+			mask = SF_FORMAT_TYPEMASK
+		} else if v, ok = lookupFormat(subtypeNames, strings.ToUpper(name)); ok {
+			mask = SF_FORMAT_SUBMASK
+		} else if v, ok = lookupFormat(endianNames, strings.ToLower(name)); ok {
+			mask = SF_FORMAT_ENDMASK
+		} else {
+			return 0, fmt.Errorf("ParseFormat: unknown format %q in %q", name, s)
+		}
+		if seen&mask != 0 {
+			return 0, fmt.Errorf("ParseFormat: %q sets %q more than once", s, name)
+		}
+		seen |= mask
+		f |= v
+	}
+	return
+}
+
+func lookupFormat(names map[Format]string, name string) (Format, bool) {
+	for f, n := range names {
+		if n == name {
+			return f, true
+		}
+	}
+	return 0, false
+}
+
+// File name extensions that libsndfile either doesn't list or lists for more than one major format. Entries that include a subtype are used as they are.
+var extensionFormats = map[string]Format{
+	"wav":  SF_FORMAT_WAV,
+	"wave": SF_FORMAT_WAV,
+	"aif":  SF_FORMAT_AIFF,
+	"aiff": SF_FORMAT_AIFF,
+	"aifc": SF_FORMAT_AIFF,
+	"snd":  SF_FORMAT_AU,
+	"ogg":  SF_FORMAT_OGG,
+	"mat":  SF_FORMAT_MAT5,
+}
+
+// Subtypes FormatForPath picks for the major formats where the first one libsndfile accepts isn't the obvious choice.
+var defaultSubtypes = map[Format]Format{
+	SF_FORMAT_WAV:   SF_FORMAT_PCM_16,
+	SF_FORMAT_WAVEX: SF_FORMAT_PCM_16,
+	SF_FORMAT_AIFF:  SF_FORMAT_PCM_16,
+	SF_FORMAT_AU:    SF_FORMAT_PCM_16,
+	SF_FORMAT_RAW:   SF_FORMAT_PCM_16,
+	SF_FORMAT_W64:   SF_FORMAT_PCM_16,
+	SF_FORMAT_RF64:  SF_FORMAT_PCM_16,
+	SF_FORMAT_CAF:   SF_FORMAT_PCM_16,
+	SF_FORMAT_FLAC:  SF_FORMAT_PCM_16,
+	SF_FORMAT_OGG:   SF_FORMAT_VORBIS,
+	SF_FORMAT_MAT4:  SF_FORMAT_DOUBLE,
+	SF_FORMAT_MAT5:  SF_FORMAT_DOUBLE,
+}
+
+// FormatForPath guesses the Format for writing a file from the extension of name, for example SF_FORMAT_FLAC|SF_FORMAT_PCM_16 for "take1.flac". Extensions are matched against the ones libsndfile reports for its major formats, plus a few common aliases such as .aif, .ogg and .opus. The subtype is 16 bit PCM where the major format allows it, Vorbis for Ogg, and otherwise the first subtype libsndfile accepts.
+// An error is returned if the extension isn't known to this build of libsndfile.
+func FormatForPath(name string) (f Format, err error) {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(name), "."))
+	if ext == "" {
+		return 0, fmt.Errorf("FormatForPath: %q has no extension", name)
+	}
+	majors := MajorFormats()
+	if m, ok := extensionFormats[ext]; ok {
+		if m.Subtype() != 0 {
+			if !FormatCheck(Info{Channels: 1, Samplerate: 48000, Format: m}) {
+				return 0, fmt.Errorf("FormatForPath: %s for extension %q is not supported by this libsndfile", m, ext)
+			}
+			return m, nil
+		}
+		for _, fi := range majors {
+			if fi.Format == m.Major() {
+				f = fi.Format
+				break
+			}
+		}
+	} else {
+		for _, fi := range majors {
+			if strings.ToLower(fi.Extension) == ext {
+				f = fi.Format
+				break
+			}
+		}
+	}
+	if f == 0 {
+		return 0, fmt.Errorf("FormatForPath: no format for extension %q", ext)
+	}
+	if sub, ok := defaultSubtypes[f]; ok && FormatCheck(Info{Channels: 1, Samplerate: 48000, Format: f | sub}) {
+		return f | sub, nil
+	}
+	if subs := SupportedSubtypes(f); len(subs) > 0 {
+		return f | subs[0].Format, nil
+	}
+	return 0, fmt.Errorf("FormatForPath: no subtype can be written to %s", f)
+}
+
+// Validate checks that i can be used to open a file for writing. It does the same check as FormatCheck, but the error says which field is the problem.
+func (i Info) Validate() error {
+	if i.Channels < 1 {
+		return fmt.Errorf("Validate: channel count %d must be at least 1", i.Channels)
+	}
+	if i.Samplerate < 1 {
+		return fmt.Errorf("Validate: sample rate %d must be at least 1", i.Samplerate)
+	}
+	if i.Format.Major() == 0 {
+		return fmt.Errorf("Validate: format %s has no major format", i.Format)
+	}
+	if i.Format.Subtype() == 0 {
+		return fmt.Errorf("Validate: format %s has no subtype", i.Format)
+	}
+	if FormatCheck(i) {
+		return nil
+	}
+	if !knownFormat(MajorFormats(), i.Format.Major()) {
+		return fmt.Errorf("Validate: major format %s is not supported by this libsndfile", i.Format.Major())
+	}
+	if !knownFormat(SubFormats(), i.Format.Subtype()) {
+		return fmt.Errorf("Validate: subtype %s is not supported by this libsndfile", i.Format.Subtype())
+	}
+	probe := Info{Channels: 1, Samplerate: i.Samplerate, Format: i.Format.Major() | i.Format.Subtype()}
+	if !FormatCheck(probe) {
+		probe.Samplerate = 48000
+		if !FormatCheck(probe) {
+			return fmt.Errorf("Validate: subtype %s can't be used in %s files", i.Format.Subtype(), i.Format.Major())
+		}
+		return fmt.Errorf("Validate: sample rate %d is not valid for %s", i.Samplerate, probe.Format)
+	}
+	probe.Format = i.Format
+	if !FormatCheck(probe) {
+		return fmt.Errorf("Validate: endian-ness %v is not valid for %s", endianNames[i.Format.Endian()], i.Format.Major()|i.Format.Subtype())
+	}
+	return fmt.Errorf("Validate: %d channels are not valid for %s", i.Channels, i.Format)
+}
+
+func knownFormat(l []FormatInfo, f Format) bool {
+	for _, fi := range l {
+		if fi.Format == f {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+// +build !legacy
+
+package sndfile
+
+// Formats added in libsndfile 1.0.26 and later. Older versions of the library reject them in FormatCheck and Open, so they are safe to use with any non-legacy build; check with SupportedSubtypes before offering them.
+const (
+	SF_FORMAT_MPEG Format = 0x230000 /* MPEG-1/2 audio stream */
+
+	SF_FORMAT_NMS_ADPCM_16 Format = 0x0022 /* 16kbs NMS G721-variant encoding. */
+	SF_FORMAT_NMS_ADPCM_24 Format = 0x0023 /* 24kbs NMS G721-variant encoding. */
+	SF_FORMAT_NMS_ADPCM_32 Format = 0x0024 /* 32kbs NMS G721-variant encoding. */
+
+	SF_FORMAT_OPUS Format = 0x0064 /* Xiph/Skype Opus encoding. */
+
+	SF_FORMAT_ALAC_16 Format = 0x0070 /* Apple Lossless Audio Codec (16 bit). */
+	SF_FORMAT_ALAC_20 Format = 0x0071 /* Apple Lossless Audio Codec (20 bit). */
+	SF_FORMAT_ALAC_24 Format = 0x0072 /* Apple Lossless Audio Codec (24 bit). */
+	SF_FORMAT_ALAC_32 Format = 0x0073 /* Apple Lossless Audio Codec (32 bit). */
+
+	SF_FORMAT_MPEG_LAYER_I   Format = 0x0080 /* MPEG-1 Audio Layer I */
+	SF_FORMAT_MPEG_LAYER_II  Format = 0x0081 /* MPEG-1 Audio Layer II */
+	SF_FORMAT_MPEG_LAYER_III Format = 0x0082 /* MPEG-2 Audio Layer III */
+)
+
+func init() {
+	majorNames[SF_FORMAT_MPEG] = "MPEG"
+	for f, n := range map[Format]string{
+		SF_FORMAT_NMS_ADPCM_16:   "NMS_ADPCM_16",
+		SF_FORMAT_NMS_ADPCM_24:   "NMS_ADPCM_24",
+		SF_FORMAT_NMS_ADPCM_32:   "NMS_ADPCM_32",
+		SF_FORMAT_OPUS:           "OPUS",
+		SF_FORMAT_ALAC_16:        "ALAC_16",
+		SF_FORMAT_ALAC_20:        "ALAC_20",
+		SF_FORMAT_ALAC_24:        "ALAC_24",
+		SF_FORMAT_ALAC_32:        "ALAC_32",
+		SF_FORMAT_MPEG_LAYER_I:   "MPEG_LAYER_I",
+		SF_FORMAT_MPEG_LAYER_II:  "MPEG_LAYER_II",
+		SF_FORMAT_MPEG_LAYER_III: "MPEG_LAYER_III",
+	} {
+		subtypeNames[f] = n
+	}
+	extensionFormats["opus"] = SF_FORMAT_OGG | SF_FORMAT_OPUS
+	extensionFormats["mp3"] = SF_FORMAT_MPEG | SF_FORMAT_MPEG_LAYER_III
+	defaultSubtypes[SF_FORMAT_MPEG] = SF_FORMAT_MPEG_LAYER_III
+}
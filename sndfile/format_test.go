@@ -0,0 +1,141 @@
+package sndfile
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatParts(t *testing.T) {
+	f := SF_FORMAT_WAV | SF_FORMAT_PCM_24 | SF_ENDIAN_LITTLE
+	if f.Major() != SF_FORMAT_WAV {
+		t.Errorf("Major: expected %v got %v", SF_FORMAT_WAV, f.Major())
+	}
+	if f.Subtype() != SF_FORMAT_PCM_24 {
+		t.Errorf("Subtype: expected %v got %v", SF_FORMAT_PCM_24, f.Subtype())
+	}
+	if f.Endian() != SF_ENDIAN_LITTLE {
+		t.Errorf("Endian: expected %v got %v", SF_ENDIAN_LITTLE, f.Endian())
+	}
+}
+
+func TestFormatString(t *testing.T) {
+	tests := []struct {
+		f Format
+		s string
+	}{
+		{SF_FORMAT_WAV | SF_FORMAT_PCM_24 | SF_ENDIAN_LITTLE, "WAV/PCM_24/little"},
+		{SF_FORMAT_FLAC | SF_FORMAT_PCM_16, "FLAC/PCM_16"},
+		{SF_FORMAT_OGG | SF_FORMAT_VORBIS, "OGG/VORBIS"},
+		{SF_FORMAT_AIFF | SF_FORMAT_FLOAT | SF_ENDIAN_CPU, "AIFF/FLOAT/cpu"},
+		{SF_FORMAT_PCM_U8, "PCM_U8"},
+		{SF_FORMAT_RAW, "RAW"},
+		{0, "0"},
+		{0x7f0000 | SF_FORMAT_PCM_16, "0x007f0000/PCM_16"},
+	}
+	for _, test := range tests {
+		if s := test.f.String(); s != test.s {
+			t.Errorf("expected %q got %q", test.s, s)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		s string
+		f Format
+	}{
+		{"flac/pcm_16", SF_FORMAT_FLAC | SF_FORMAT_PCM_16},
+		{"WAV/PCM_24/little", SF_FORMAT_WAV | SF_FORMAT_PCM_24 | SF_ENDIAN_LITTLE},
+		{" Ogg / Vorbis ", SF_FORMAT_OGG | SF_FORMAT_VORBIS},
+		{"aiff", SF_FORMAT_AIFF},
+		{"double/big", SF_FORMAT_DOUBLE | SF_ENDIAN_BIG},
+	}
+	for _, test := range tests {
+		f, err := ParseFormat(test.s)
+		if err != nil {
+			t.Errorf("ParseFormat(%q) failed: %v", test.s, err)
+		} else if f != test.f {
+			t.Errorf("ParseFormat(%q): expected %v got %v", test.s, test.f, f)
+		}
+	}
+	for _, s := range []string{"", "mp5/pcm_16", "wav/aiff", "wav/pcm_16/big/little", "wav//pcm_16"} {
+		if f, err := ParseFormat(s); err == nil {
+			t.Errorf("ParseFormat(%q) accepted, got %v", s, f)
+		}
+	}
+	for f := range majorNames {
+		for s := range subtypeNames {
+			for e := range endianNames {
+				ff, err := ParseFormat((f | s | e).String())
+				if err != nil || ff != f|s|e {
+					t.Errorf("%v doesn't round trip: got %v, %v", f|s|e, ff, err)
+				}
+			}
+		}
+	}
+}
+
+func TestFormatForPath(t *testing.T) {
+	tests := []struct {
+		name string
+		f    Format
+	}{
+		{"take1.wav", SF_FORMAT_WAV | SF_FORMAT_PCM_16},
+		{"/tmp/LOOP.AIF", SF_FORMAT_AIFF | SF_FORMAT_PCM_16},
+		{"x.aiff", SF_FORMAT_AIFF | SF_FORMAT_PCM_16},
+		{"x.flac", SF_FORMAT_FLAC | SF_FORMAT_PCM_16},
+		{"x.ogg", SF_FORMAT_OGG | SF_FORMAT_VORBIS},
+		{"x.oga", SF_FORMAT_OGG | SF_FORMAT_VORBIS},
+		{"x.caf", SF_FORMAT_CAF | SF_FORMAT_PCM_16},
+		{"x.w64", SF_FORMAT_W64 | SF_FORMAT_PCM_16},
+		{"x.rf64", SF_FORMAT_RF64 | SF_FORMAT_PCM_16},
+		{"x.au", SF_FORMAT_AU | SF_FORMAT_PCM_16},
+		{"x.mat", SF_FORMAT_MAT5 | SF_FORMAT_DOUBLE},
+	}
+	for _, test := range tests {
+		f, err := FormatForPath(test.name)
+		if err != nil {
+			t.Errorf("FormatForPath(%q) failed: %v", test.name, err)
+			continue
+		}
+		if f != test.f {
+			t.Errorf("FormatForPath(%q): expected %v got %v", test.name, test.f, f)
+		}
+		if err = (Info{Channels: 2, Samplerate: 44100, Format: f}).Validate(); err != nil {
+			t.Errorf("format for %q doesn't validate: %v", test.name, err)
+		}
+	}
+	for _, name := range []string{"noextension", "x.mp5", "x."} {
+		if f, err := FormatForPath(name); err == nil {
+			t.Errorf("FormatForPath(%q) accepted, got %v", name, f)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	good := Info{Channels: 2, Samplerate: 44100, Format: SF_FORMAT_WAV | SF_FORMAT_PCM_16}
+	if err := good.Validate(); err != nil {
+		t.Error("valid info rejected:", err)
+	}
+	tests := []struct {
+		i    Info
+		want string
+	}{
+		{Info{Channels: 0, Samplerate: 44100, Format: SF_FORMAT_WAV | SF_FORMAT_PCM_16}, "channel count"},
+		{Info{Channels: 2, Samplerate: 0, Format: SF_FORMAT_WAV | SF_FORMAT_PCM_16}, "sample rate"},
+		{Info{Channels: 2, Samplerate: 44100, Format: SF_FORMAT_PCM_16}, "no major format"},
+		{Info{Channels: 2, Samplerate: 44100, Format: SF_FORMAT_WAV}, "no subtype"},
+		{Info{Channels: 2, Samplerate: 44100, Format: 0x7f0000 | SF_FORMAT_PCM_16}, "major format"},
+		{Info{Channels: 2, Samplerate: 44100, Format: SF_FORMAT_WAV | SF_FORMAT_VORBIS}, "subtype VORBIS can't be used in WAV"},
+		{Info{Channels: 2, Samplerate: 44100, Format: SF_FORMAT_FLAC | SF_FORMAT_PCM_16 | SF_ENDIAN_BIG}, "endian-ness big"},
+		{Info{Channels: 2, Samplerate: 44100, Format: SF_FORMAT_XI | SF_FORMAT_DPCM_16}, "2 channels"},
+	}
+	for _, test := range tests {
+		err := test.i.Validate()
+		if err == nil {
+			t.Errorf("%+v validated", test.i)
+		} else if !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%+v: expected error about %q, got %v", test.i, test.want, err)
+		}
+	}
+}
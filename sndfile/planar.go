@@ -0,0 +1,199 @@
+package sndfile
+
+import (
+	"fmt"
+	"io"
+)
+
+// sample is the set of types libsndfile converts audio to and from.
+type sample interface {
+	int16 | int32 | float32 | float64
+}
+
+// InterleaveInt16 copies frames from the per-channel slices in src into dst, one sample from each channel in turn. It returns the number of frames copied, which is limited by the shortest channel and by the room in dst.
+func InterleaveInt16(dst []int16, src [][]int16) int { return interleave(dst, src) }
+
+// DeinterleaveInt16 is the inverse of InterleaveInt16, splitting the frames in src into one slice per channel.
+func DeinterleaveInt16(dst [][]int16, src []int16) int { return deinterleave(dst, src) }
+
+// InterleaveInt32 is InterleaveInt16 for int32 samples.
+func InterleaveInt32(dst []int32, src [][]int32) int { return interleave(dst, src) }
+
+// DeinterleaveInt32 is DeinterleaveInt16 for int32 samples.
+func DeinterleaveInt32(dst [][]int32, src []int32) int { return deinterleave(dst, src) }
+
+// InterleaveFloat32 is InterleaveInt16 for float32 samples.
+func InterleaveFloat32(dst []float32, src [][]float32) int { return interleave(dst, src) }
+
+// DeinterleaveFloat32 is DeinterleaveInt16 for float32 samples.
+func DeinterleaveFloat32(dst [][]float32, src []float32) int { return deinterleave(dst, src) }
+
+// InterleaveFloat64 is InterleaveInt16 for float64 samples.
+func InterleaveFloat64(dst []float64, src [][]float64) int { return interleave(dst, src) }
+
+// DeinterleaveFloat64 is DeinterleaveInt16 for float64 samples.
+func DeinterleaveFloat64(dst [][]float64, src []float64) int { return deinterleave(dst, src) }
+
+func interleave[T sample](dst []T, src [][]T) int {
+	if len(src) == 0 {
+		return 0
+	}
+	frames := minInt(len(dst)/len(src), shortest(src))
+	for c, ch := range src {
+		for i, v := range ch[:frames] {
+			dst[i*len(src)+c] = v
+		}
+	}
+	return frames
+}
+
+func deinterleave[T sample](dst [][]T, src []T) int {
+	if len(dst) == 0 {
+		return 0
+	}
+	frames := minInt(len(src)/len(dst), shortest(dst))
+	for c, ch := range dst {
+		for i := range ch[:frames] {
+			ch[i] = src[i*len(dst)+c]
+		}
+	}
+	return frames
+}
+
+// shortest returns the length of the shortest slice in bufs, which must not be empty.
+func shortest[T sample](bufs [][]T) int {
+	frames := len(bufs[0])
+	for _, b := range bufs[1:] {
+		if len(b) < frames {
+			frames = len(b)
+		}
+	}
+	return frames
+}
+
+// Frames moved per libsndfile call by the planar functions, so they don't need an interleaved copy of the whole buffer.
+const planarChunk = 4096
+
+// ReadFramesPlanar reads float32 frames into one slice per channel, as used by most DSP code. out must have a slice for each channel in the file; as many frames are read as fit in the shortest one. A mono file is read straight into out[0].
+// Returns the number of frames read, which is 0 at the end of the file, or io.EOF if the buffers have no room, like ReadFrames.
+func (f *File) ReadFramesPlanar(out [][]float32) (read int64, err error) {
+	return readPlanar(f, "ReadFramesPlanar", out, f.readFramesFloat32Locked)
+}
+
+// ReadFramesPlanarInt16 is ReadFramesPlanar for int16 samples.
+func (f *File) ReadFramesPlanarInt16(out [][]int16) (read int64, err error) {
+	return readPlanar(f, "ReadFramesPlanarInt16", out, f.readFramesInt16Locked)
+}
+
+// ReadFramesPlanarInt32 is ReadFramesPlanar for int32 samples.
+func (f *File) ReadFramesPlanarInt32(out [][]int32) (read int64, err error) {
+	return readPlanar(f, "ReadFramesPlanarInt32", out, f.readFramesInt32Locked)
+}
+
+// ReadFramesPlanarFloat64 is ReadFramesPlanar for float64 samples.
+func (f *File) ReadFramesPlanarFloat64(out [][]float64) (read int64, err error) {
+	return readPlanar(f, "ReadFramesPlanarFloat64", out, f.readFramesFloat64Locked)
+}
+
+// WriteFramesPlanar is the inverse of ReadFramesPlanar, writing one float32 slice per channel. Only as many frames as there are in the shortest slice are written.
+func (f *File) WriteFramesPlanar(in [][]float32) (written int64, err error) {
+	return writePlanar(f, "WriteFramesPlanar", in, f.writeFramesFloat32Locked)
+}
+
+// WriteFramesPlanarInt16 is WriteFramesPlanar for int16 samples.
+func (f *File) WriteFramesPlanarInt16(in [][]int16) (written int64, err error) {
+	return writePlanar(f, "WriteFramesPlanarInt16", in, f.writeFramesInt16Locked)
+}
+
+// WriteFramesPlanarInt32 is WriteFramesPlanar for int32 samples.
+func (f *File) WriteFramesPlanarInt32(in [][]int32) (written int64, err error) {
+	return writePlanar(f, "WriteFramesPlanarInt32", in, f.writeFramesInt32Locked)
+}
+
+// WriteFramesPlanarFloat64 is WriteFramesPlanar for float64 samples.
+func (f *File) WriteFramesPlanarFloat64(in [][]float64) (written int64, err error) {
+	return writePlanar(f, "WriteFramesPlanarFloat64", in, f.writeFramesFloat64Locked)
+}
+
+// readPlanar does the work of the ReadFramesPlanar methods, with readFrames the matching ...Locked read.
+func readPlanar[T sample](f *File, op string, out [][]T, readFrames func([]T) (int64, error)) (read int64, err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	channels := int(f.Format.Channels)
+	if len(out) != channels {
+		return 0, fmt.Errorf("%s: %d buffers for %d channels", op, len(out), channels)
+	}
+	frames := shortest(out)
+	if channels == 1 {
+		return readFrames(out[0][:frames])
+	}
+	if frames < 1 {
+		return 0, io.EOF
+	}
+	buf := make([]T, minInt(frames, planarChunk)*channels)
+	sub := make([][]T, channels)
+	for read < int64(frames) {
+		want := minInt(frames-int(read), planarChunk)
+		n, err := readFrames(buf[:want*channels])
+		if n > 0 {
+			for c := range sub {
+				sub[c] = out[c][read : read+n]
+			}
+			deinterleave(sub, buf[:int(n)*channels])
+			read += n
+		}
+		if err != nil {
+			if read > 0 && err == io.EOF {
+				err = nil
+			}
+			return read, err
+		}
+		if n < int64(want) {
+			break
+		}
+	}
+	return read, nil
+}
+
+// writePlanar does the work of the WriteFramesPlanar methods, with writeFrames the matching ...Locked write.
+func writePlanar[T sample](f *File, op string, in [][]T, writeFrames func([]T) (int64, error)) (written int64, err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	channels := int(f.Format.Channels)
+	if len(in) != channels {
+		return 0, fmt.Errorf("%s: %d buffers for %d channels", op, len(in), channels)
+	}
+	frames := shortest(in)
+	if channels == 1 {
+		return writeFrames(in[0][:frames])
+	}
+	if frames < 1 {
+		return 0, io.EOF
+	}
+	buf := make([]T, minInt(frames, planarChunk)*channels)
+	sub := make([][]T, channels)
+	for written < int64(frames) {
+		want := minInt(frames-int(written), planarChunk)
+		for c := range sub {
+			sub[c] = in[c][written : written+int64(want)]
+		}
+		interleave(buf, sub)
+		n, err := writeFrames(buf[:want*channels])
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,183 @@
+package sndfile
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+var planarChannels = []int{1, 2, 3, 4, 5, 6, 7, 8, 11}
+
+// planarData returns channels slices of n samples, each sample distinct.
+func planarData(channels, n int) [][]float32 {
+	data := make([][]float32, channels)
+	for c := range data {
+		data[c] = make([]float32, n)
+		for i := range data[c] {
+			data[c][i] = float32(c*n+i) / float32(channels*n)
+		}
+	}
+	return data
+}
+
+func TestInterleave(t *testing.T) {
+	for _, channels := range planarChannels {
+		const n = 10
+		src := planarData(channels, n)
+		flat := make([]float32, channels*n)
+		if f := InterleaveFloat32(flat, src); f != n {
+			t.Fatalf("%d channels: interleaved %d frames, expected %d", channels, f, n)
+		}
+		for i := 0; i < n; i++ {
+			for c := 0; c < channels; c++ {
+				if flat[i*channels+c] != src[c][i] {
+					t.Fatalf("%d channels: frame %d channel %d is %v, expected %v", channels, i, c, flat[i*channels+c], src[c][i])
+				}
+			}
+		}
+		out := make([][]float32, channels)
+		for c := range out {
+			out[c] = make([]float32, n)
+		}
+		if f := DeinterleaveFloat32(out, flat); f != n || !reflect.DeepEqual(out, src) {
+			t.Errorf("%d channels: float32 doesn't round trip, %d frames", channels, f)
+		}
+
+		src64, out64 := make([][]float64, channels), make([][]float64, channels)
+		src32, out32 := make([][]int32, channels), make([][]int32, channels)
+		src16, out16 := make([][]int16, channels), make([][]int16, channels)
+		for c := range src {
+			src64[c], out64[c] = make([]float64, n), make([]float64, n)
+			src32[c], out32[c] = make([]int32, n), make([]int32, n)
+			src16[c], out16[c] = make([]int16, n), make([]int16, n)
+			for i, v := range src[c] {
+				src64[c][i] = float64(v)
+				src32[c][i] = int32(v * (1 << 30))
+				src16[c][i] = int16(v * (1 << 14))
+			}
+		}
+		flat64 := make([]float64, channels*n)
+		InterleaveFloat64(flat64, src64)
+		if f := DeinterleaveFloat64(out64, flat64); f != n || !reflect.DeepEqual(out64, src64) {
+			t.Errorf("%d channels: float64 doesn't round trip, %d frames", channels, f)
+		}
+		flat32 := make([]int32, channels*n)
+		InterleaveInt32(flat32, src32)
+		if f := DeinterleaveInt32(out32, flat32); f != n || !reflect.DeepEqual(out32, src32) {
+			t.Errorf("%d channels: int32 doesn't round trip, %d frames", channels, f)
+		}
+		flat16 := make([]int16, channels*n)
+		InterleaveInt16(flat16, src16)
+		if f := DeinterleaveInt16(out16, flat16); f != n || !reflect.DeepEqual(out16, src16) {
+			t.Errorf("%d channels: int16 doesn't round trip, %d frames", channels, f)
+		}
+	}
+}
+
+func TestInterleaveShort(t *testing.T) {
+	src := [][]int16{{1, 2, 3, 4}, {5, 6}, {7, 8, 9}}
+	dst := make([]int16, 20)
+	if n := InterleaveInt16(dst, src); n != 2 || !reflect.DeepEqual(dst[:6], []int16{1, 5, 7, 2, 6, 8}) {
+		t.Errorf("interleaved %d frames, %v", n, dst[:6])
+	}
+	if n := InterleaveInt16(dst[:4], src); n != 1 {
+		t.Errorf("interleaved %d frames into room for 1", n)
+	}
+	if n := DeinterleaveInt16([][]int16{make([]int16, 5), make([]int16, 5)}, dst[:7]); n != 3 {
+		t.Errorf("deinterleaved %d frames from 3 and a bit", n)
+	}
+	if n := InterleaveInt16(dst, nil); n != 0 {
+		t.Errorf("interleaved %d frames with no channels", n)
+	}
+}
+
+func TestPlanarFile(t *testing.T) {
+	defer os.Remove("planar.wav")
+	// more than one chunk, and not a multiple of it
+	const n = 2*planarChunk + 123
+	for _, channels := range planarChannels {
+		in := planarData(channels, n)
+		i := Info{Channels: int32(channels), Samplerate: 44100, Format: SF_FORMAT_WAV | SF_FORMAT_FLOAT}
+		f, err := Open("planar.wav", Write, &i)
+		if err != nil {
+			t.Fatalf("%d channels: couldn't open file for write: %v", channels, err)
+		}
+		if _, err = f.WriteFramesPlanar(in[:len(in)-1]); err == nil {
+			t.Errorf("%d channels: WriteFramesPlanar accepted %d buffers", channels, channels-1)
+		}
+		if w, err := f.WriteFramesPlanar(in); w != n || err != nil {
+			t.Fatalf("%d channels: wrote %d of %d frames: %v", channels, w, n, err)
+		}
+		f.Close()
+
+		f, err = Open("planar.wav", Read, &i)
+		if err != nil {
+			t.Fatalf("%d channels: couldn't open file for read: %v", channels, err)
+		}
+		out := planarData(channels, n+10)
+		if r, err := f.ReadFramesPlanar(out); r != n || err != nil {
+			t.Fatalf("%d channels: read %d of %d frames: %v", channels, r, n, err)
+		}
+		for c := range out {
+			if !reflect.DeepEqual(out[c][:n], in[c]) {
+				t.Errorf("%d channels: channel %d doesn't match", channels, c)
+			}
+		}
+		if r, err := f.ReadFramesPlanar(out); r != 0 || err != nil {
+			t.Errorf("%d channels: read %d frames at end of file: %v", channels, r, err)
+		}
+		f.Close()
+	}
+}
+
+func TestPlanarFileTyped(t *testing.T) {
+	defer os.Remove("planar.wav")
+	const channels, n = 3, planarChunk + 5
+	in := make([][]int32, channels)
+	for c := range in {
+		in[c] = make([]int32, n)
+		for i := range in[c] {
+			in[c][i] = int32(c*n+i) << 16
+		}
+	}
+	i := Info{Channels: channels, Samplerate: 44100, Format: SF_FORMAT_WAV | SF_FORMAT_PCM_16}
+	f, err := Open("planar.wav", Write, &i)
+	if err != nil {
+		t.Fatal("couldn't open file for write", err)
+	}
+	if w, err := f.WriteFramesPlanarInt32(in); w != n || err != nil {
+		t.Fatalf("wrote %d of %d frames: %v", w, n, err)
+	}
+	f.Close()
+
+	f, err = Open("planar.wav", Read, &i)
+	if err != nil {
+		t.Fatal("couldn't open file for read", err)
+	}
+	defer f.Close()
+	out16 := make([][]int16, channels)
+	for c := range out16 {
+		out16[c] = make([]int16, n)
+	}
+	if r, err := f.ReadFramesPlanarInt16(out16); r != n || err != nil {
+		t.Fatalf("read %d of %d int16 frames: %v", r, n, err)
+	}
+	f.Seek(0, Set)
+	out64 := make([][]float64, channels)
+	for c := range out64 {
+		out64[c] = make([]float64, n)
+	}
+	if r, err := f.ReadFramesPlanarFloat64(out64); r != n || err != nil {
+		t.Fatalf("read %d of %d float64 frames: %v", r, n, err)
+	}
+	for c := range in {
+		for k := range in[c] {
+			if v := int32(out16[c][k]) << 16; v != in[c][k] {
+				t.Fatalf("channel %d frame %d is %#x as int16, expected %#x", c, k, v, in[c][k])
+			}
+			if v := out64[c][k] * 32768; v != float64(in[c][k]>>16) {
+				t.Fatalf("channel %d frame %d is %v as float64, expected %v", c, k, v, in[c][k]>>16)
+			}
+		}
+	}
+}
@@ -11,11 +11,16 @@ import "unsafe"
 //The raw read and write functions read raw audio data from the audio file (not to be confused with reading RAW header-less PCM files). The number of bytes read or written must always be an integer multiple of the number of channels multiplied by the number of bytes required to represent one sample from one channel.
 
 //The raw read and write functions return the number of bytes read or written (which should be the same as the bytes parameter) and any error that occurs while reading or writing
+//The convert subpackage packs and unpacks raw PCM data; pass RawNeedsEndianSwap to convert.RawLayout to get the byte order right.
 // needs test
 func (f *File) ReadRaw(data []byte) (read int64, err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
 	read = int64(C.sf_read_raw(f.s, unsafe.Pointer(&data[0]), C.sf_count_t(len(data))))
 	if read != int64(len(data)) {
-		err = sErrorType(C.sf_error(f.s))
+		err = f.error("ReadRaw")
 	}
 	return
 }
@@ -25,11 +30,20 @@ func (f *File) ReadRaw(data []byte) (read int64, err error) {
 //The raw read and write functions read raw audio data from the audio file (not to be confused with reading RAW header-less PCM files). The number of bytes read or written must always be an integer multiple of the number of channels multiplied by the number of bytes required to represent one sample from one channel.
 
 //The raw read and write functions return the number of bytes read or written (which should be the same as the bytes parameter) and any error that occurs while reading or writing
+//The convert subpackage packs and unpacks raw PCM data; pass RawNeedsEndianSwap to convert.RawLayout to get the byte order right.
 // needs test
 func (f *File) WriteRaw(data []byte) (written int64, err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
 	written = int64(C.sf_write_raw(f.s, unsafe.Pointer(&data[0]), C.sf_count_t(len(data))))
+	if written > 0 {
+		f.written = true
+		f.updateFrames()
+	}
 	if written != int64(len(data)) {
-		err = sErrorType(C.sf_error(f.s))
+		err = f.error("WriteRaw")
 	}
 	return
 }
@@ -11,28 +11,121 @@ import (
 	"errors"
 	"io"
 	"os"
-	"reflect"
 	"runtime"
+	"strings"
+	"sync"
 	"unsafe"
 )
 
 // A sound file. Does not conform to io.Reader.
+// A File may be used from several goroutines at once; each method holds an internal lock for the duration of its libsndfile call.
 type File struct {
+	mu      sync.Mutex // guards the fields below
 	s       *C.SNDFILE
-	Format  Info
-	virtual *virtualIo // really only necessary to keep a reference so GC doesn't eat it
+	name    string     // only set by Open, used in errors
+	Format  Info       // Frames is kept current after each write and Truncate, see Info
+	virtual *virtualIo // registry entry for OpenVirtual files, released on Close
 	fd      uintptr
 	closeFd bool
 	closed  bool
+	mode    Mode
+	written bool         // set by the first successful write, encoder settings can't change after that
+	dither  *ditherState // Go side dither for float writes, see SetDitherOnWrite
 }
 
-// sErrorType represents a sndfile API error and grabs error description strings from the API.
-type sErrorType C.int
+// ErrClosed is returned by methods called on a File after Close. Methods without an error result return their zero value instead.
+var ErrClosed = errors.New("sndfile: file already closed")
 
-func (e sErrorType) Error() string {
+// lock takes the file lock, or returns ErrClosed without holding it. Callers unlock f.mu when lock succeeds.
+func (f *File) lock() error {
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return ErrClosed
+	}
+	return nil
+}
+
+// ErrorCode represents a sndfile API error number and grabs error description strings from the API.
+type ErrorCode int
+
+func (e ErrorCode) Error() string {
 	return C.GoString(C.sf_error_number(C.int(e)))
 }
 
+// The public error numbers from sndfile.h. libsndfile also reports more specific internal numbers, which have no names here but still come back in Error.Code. Use errors.Is to compare them with an error returned from this package; an internal number matches the public one it falls under, see Error.Is.
+const (
+	ErrUnrecognisedFormat  ErrorCode = C.SF_ERR_UNRECOGNISED_FORMAT
+	ErrSystem              ErrorCode = C.SF_ERR_SYSTEM
+	ErrMalformedFile       ErrorCode = C.SF_ERR_MALFORMED_FILE
+	ErrUnsupportedEncoding ErrorCode = C.SF_ERR_UNSUPPORTED_ENCODING
+)
+
+// Error is returned when a libsndfile call fails. It records the operation, the file name if known, and the libsndfile error number, and unwraps to its Code.
+type Error struct {
+	Op   string    // the method or function that failed
+	Path string    // the name passed to Open, empty for other files
+	Code ErrorCode // libsndfile's error number
+	Msg  string    // libsndfile's description, which is often more detailed than Code's
+}
+
+func (e *Error) Error() string {
+	if e.Path == "" {
+		return e.Op + ": " + e.Msg
+	}
+	return e.Op + " " + e.Path + ": " + e.Msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Code
+}
+
+// Is reports whether target is the public error number that e's Code falls under, so errors.Is matches the sentinels above for libsndfile's internal numbers too.
+func (e *Error) Is(target error) bool {
+	c, ok := target.(ErrorCode)
+	return ok && c == e.Code.category()
+}
+
+// category sorts an internal error number into one of the public ones. libsndfile doesn't publish its internal numbers, which change between versions, so this goes by their descriptions: unknown or unimplemented encodings, header errors from the format readers ("Error in WAV file..."), and failed system calls. Numbers that fit none of these are returned unchanged.
+func (c ErrorCode) category() ErrorCode {
+	if c <= ErrUnsupportedEncoding {
+		return c
+	}
+	msg := strings.ToLower(c.Error())
+	switch {
+	case containsAny(msg, "unsupported", "not supported", "unimplemented", "unknown format", "unknown encoding"):
+		return ErrUnsupportedEncoding
+	case strings.HasPrefix(msg, "error in "), strings.Contains(msg, "malformed"):
+		return ErrMalformedFile
+	case containsAny(msg, "system error", "malloc", "could not open", "does not exist"):
+		return ErrSystem
+	}
+	return c
+}
+
+func containsAny(s string, subs ...string) bool {
+	for _, sub := range subs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// newError collects the current error state of s, which may be nil to get the error from the last failed open.
+func newError(op, path string, s *C.SNDFILE) error {
+	return &Error{op, path, ErrorCode(C.sf_error(s)), C.GoString(C.sf_strerror(s))}
+}
+
+func (f *File) error(op string) error {
+	return newError(op, f.name, f.s)
+}
+
+// codeError wraps an error number returned directly by a command.
+func (f *File) codeError(op string, code C.int) error {
+	return &Error{op, f.name, ErrorCode(code), ErrorCode(code).Error()}
+}
+
 // File mode: Read, Write, or ReadWrite
 type Mode int
 
@@ -50,6 +143,9 @@ type Info struct {
 	Format     Format
 	Sections   int32
 	Seekable   int32
+
+	// OriginalSamplerate is the rate of the audio before it was encoded, for formats such as Opus that record it. Only File.Info fills it in, and it is zero when the file has none. It is ignored when opening a file; use SetOriginalSamplerate to store it when writing.
+	OriginalSamplerate int32
 }
 
 func (i Info) toCinfo() (out *C.SF_INFO) {
@@ -73,6 +169,25 @@ func fromCinfo(i *C.SF_INFO) (out Info) {
 	return out
 }
 
+// updateFrames copies the file's current length into f.Format.Frames. The caller holds the lock.
+func (f *File) updateFrames() {
+	if frames, ok := f.currentFrames(); ok {
+		f.Format.Frames = frames
+	}
+}
+
+// Info returns the current parameters of the file. Frames includes everything written or truncated so far, as f.Format.Frames does, but unlike the Format field Info is safe to call while other goroutines use f. OriginalSamplerate is set when the file records one.
+func (f *File) Info() (i Info, err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	f.updateFrames()
+	i = f.Format
+	i.OriginalSamplerate = f.originalSamplerate()
+	return i, nil
+}
+
 // The format field in the above Info structure is made up of the bit-wise OR of a major format type (values between 0x10000 and 0x08000000), a minor format type (with values less than 0x10000) and an optional endian-ness value. The currently understood formats are taken from sndfile.h as follows and also include bitmasks for separating major and minor file types. Not all combinations of endian-ness and major and minor file types are valid.
 type Format int32
 
@@ -161,12 +276,14 @@ func Open(name string, mode Mode, info *Info) (o *File, err error) {
 		return nil, errors.New("nil pointer passed to open")
 	}
 	o = new(File)
+	o.name = name
+	o.mode = mode
 	c := C.CString(name)
 	defer C.free(unsafe.Pointer(c))
 	ci := info.toCinfo()
 	o.s = C.sf_open(c, C.int(mode), ci)
 	if o.s == nil {
-		err = errors.New(C.GoString(C.sf_strerror(o.s)))
+		err = newError("Open", name, nil)
 	}
 	*info = fromCinfo(ci)
 	o.Format = *info
@@ -174,6 +291,23 @@ func Open(name string, mode Mode, info *Info) (o *File, err error) {
 	return
 }
 
+// OpenRF64Auto opens name for writing as an RF64 file, which has no 4GiB limit, and sets SetRF64AutoDowngrade so that a file which stays small is written as a plain WAV file on Close. The major format in info is replaced with SF_FORMAT_RF64; the subtype and endian-ness are kept.
+func OpenRF64Auto(name string, info *Info) (o *File, err error) {
+	if info == nil {
+		return nil, errors.New("nil pointer passed to open")
+	}
+	info.Format = SF_FORMAT_RF64 | info.Format&^SF_FORMAT_TYPEMASK
+	o, err = Open(name, Write, info)
+	if err != nil {
+		return
+	}
+	if err = o.SetRF64AutoDowngrade(true); err != nil {
+		o.Close()
+		return nil, err
+	}
+	return
+}
+
 // This probably won't work on windows, because go uses handles instead of integer file descriptors on Windows. Unfortunately I have no way to test.
 // The mode and info arguments, and the return values, are the same as for Open().
 // close_desc should be true if you want the library to close the file descriptor when you close the sndfile.File object
@@ -182,12 +316,13 @@ func OpenFd(fd uintptr, mode Mode, info *Info, close_desc bool) (o *File, err er
 		return nil, errors.New("nil pointer passed to open")
 	}
 	o = new(File)
+	o.mode = mode
 	o.closeFd = close_desc
 	o.fd = fd
 	ci := info.toCinfo()
 	o.s = C.sf_open_fd(C.int(fd), C.int(mode), ci, 0) // don't want libsndfile to close a Go file object from under us
 	if o.s == nil {
-		err = errors.New(C.GoString(C.sf_strerror(o.s)))
+		err = newError("OpenFd", "", nil)
 	}
 	*info = fromCinfo(ci)
 	o.Format = *info
@@ -212,30 +347,56 @@ const (
 
 //The file seek functions work much like lseek in unistd.h with the exception that the non-audio data is ignored and the seek only moves within the audio data section of the file. In addition, seeks are defined in number of (multichannel) frames. Therefore, a seek in a stereo file from the current position forward with an offset of 1 would skip forward by one sample of both channels. This function returns the new offset, and a non-nil error value if unsuccessful
 func (f *File) Seek(frames int64, w Whence) (offset int64, err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	return f.seekLocked(frames, w)
+}
+
+// seekLocked and the other ...Locked methods do the work of the method they are named after for callers that already hold the lock, so that several steps can be done as one operation.
+func (f *File) seekLocked(frames int64, w Whence) (offset int64, err error) {
 	r := C.sf_seek(f.s, C.sf_count_t(frames), C.int(w))
 	if r == -1 {
-		err = errors.New(C.GoString(C.sf_strerror(f.s)))
+		err = f.error("Seek")
 	} else {
 		offset = int64(r)
 	}
 	return
 }
 
-// The close function closes the file, deallocates its internal buffers and returns a non-nil error value in case of error
+// The close function closes the file, deallocates its internal buffers and returns a non-nil error value in case of error. Calling Close more than once is safe; later calls do nothing and return nil.
 func (f *File) Close() (err error) {
-	if C.sf_close(f.s) != 0 {
-		err = errors.New(C.GoString(C.sf_strerror(f.s)))
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+	runtime.SetFinalizer(f, nil)
+	if f.s != nil {
+		if r := C.sf_close(f.s); r != 0 {
+			err = f.codeError("Close", r)
+		}
+	}
+	f.s = nil
+	if f.virtual != nil {
+		f.virtual.free()
+		f.virtual = nil
 	}
 	if f.closeFd {
 		nf := os.NewFile(f.fd, "")
 		err = nf.Close()
 	}
-	runtime.SetFinalizer(f, nil)
 	return
 }
 
 //If the file is opened Write or ReadWrite, call the operating system's function to force the writing of all file cache buffers to disk. If the file is opened Read no action is taken.
 func (f *File) WriteSync() {
+	if f.lock() != nil {
+		return
+	}
+	defer f.mu.Unlock()
 	C.sf_write_sync(f.s)
 }
 
@@ -245,102 +406,67 @@ It is important to note that the data type used by the calling program and the d
 
 Returns the number of items read. Unless the end of the file was reached during the read, the return value should equal the number of items requested. Attempts to read beyond the end of the file will not result in an error but will cause ReadItems to return less than the number of items requested or 0 if already at the end of the file.
 
-out must be a slice or array of int, int16, int32, float32, or float64.
+out must be a slice of int16, uint16, int32, uint32, float32, or float64. The ReadItemsInt16, ReadItemsInt32, ReadItemsFloat32 and ReadItemsFloat64 methods do the same thing without the type switch.
 
 */
 func (f *File) ReadItems(out interface{}) (read int64, err error) {
-	t := reflect.TypeOf(out)
-	if t.Kind() != reflect.Array && t.Kind() != reflect.Slice {
-		return -1, errors.New("You need to give me an array!")
-	}
-
-	v := reflect.ValueOf(out)
-	l := v.Len()
-	o := v.Slice(0, l)
-	var n C.sf_count_t
-	switch t.Elem().Kind() {
-	case reflect.Int16:
-		n = C.sf_read_short(f.s, (*C.short)(unsafe.Pointer(o.Index(0).Addr().Pointer())), C.sf_count_t(l))
-	case reflect.Int32:
-		n = C.sf_read_int(f.s, (*C.int)(unsafe.Pointer(o.Index(0).Addr().Pointer())), C.sf_count_t(l))
-	case reflect.Float32:
-		n = C.sf_read_float(f.s, (*C.float)(unsafe.Pointer(o.Index(0).Addr().Pointer())), C.sf_count_t(l))
-	case reflect.Float64:
-		n = C.sf_read_double(f.s, (*C.double)(unsafe.Pointer(o.Index(0).Addr().Pointer())), C.sf_count_t(l))
-	case reflect.Int:
-		switch t.Bits() {
-		case 32:
-			n = C.sf_read_int(f.s, (*C.int)(unsafe.Pointer(o.Index(0).Addr().Pointer())), C.sf_count_t(l))
-		case 16: // doubtful
-			n = C.sf_read_short(f.s, (*C.short)(unsafe.Pointer(o.Index(0).Addr().Pointer())), C.sf_count_t(l))
-		default:
-			err = errors.New("Unsupported type in read buffer, needs (u)int16, (u)int32, or float type")
-		}
-	default:
-		err = errors.New("Unsupported type in read buffer, needs (u)int16, (u)int32, or float type")
-	}
-	if err != nil {
-		read = -1
-		return
-	}
-
-	read = int64(n)
-	if read < 0 {
-		err = errors.New(C.GoString(C.sf_strerror(f.s)))
+	switch b := out.(type) {
+	case []int16:
+		return f.ReadItemsInt16(b)
+	case []uint16:
+		return f.ReadItemsInt16(*(*[]int16)(unsafe.Pointer(&b)))
+	case []int32:
+		return f.ReadItemsInt32(b)
+	case []uint32:
+		return f.ReadItemsInt32(*(*[]int32)(unsafe.Pointer(&b)))
+	case []float32:
+		return f.ReadItemsFloat32(b)
+	case []float64:
+		return f.ReadItemsFloat64(b)
 	}
-	return
+	return -1, errors.New("Unsupported type in read buffer, needs (u)int16, (u)int32, or float type")
 }
 
 /*The file read frames functions fill the array pointed to by out with the requested number of frames of data. The array must be large enough to hold the product of frames and the number of channels.
 
-The sf_readf_XXXX functions return the number of frames read. Unless the end of the file was reached during the read, the return value should equal the number of frames requested. Attempts to read beyond the end of the file will not result in an error but will cause the sf_readf_XXXX functions to return less than the number of frames requested or 0 if already at the end of the file.*/
-func (f *File) ReadFrames(out interface{}) (read int64, err error) {
-	t := reflect.TypeOf(out)
-	if t.Kind() != reflect.Array && t.Kind() != reflect.Slice {
-		return -1, errors.New("You need to give me an array!")
-	}
+The sf_readf_XXXX functions return the number of frames read. Unless the end of the file was reached during the read, the return value should equal the number of frames requested. Attempts to read beyond the end of the file will not result in an error but will cause the sf_readf_XXXX functions to return less than the number of frames requested or 0 if already at the end of the file.
 
-	v := reflect.ValueOf(out)
-	l := v.Len()
-	o := v.Slice(0, l)
-	frames := l / int(f.Format.Channels)
-	if frames < 1 {
-		err = io.EOF
-		return
-	}
-	var n C.sf_count_t
-	p := unsafe.Pointer(o.Index(0).Addr().Pointer())
-	switch t.Elem().Kind() {
-	case reflect.Int16, reflect.Uint16:
-		n = C.sf_readf_short(f.s, (*C.short)(p), C.sf_count_t(frames))
-	case reflect.Int32, reflect.Uint32:
-		n = C.sf_readf_int(f.s, (*C.int)(p), C.sf_count_t(frames))
-	case reflect.Float32:
-		n = C.sf_readf_float(f.s, (*C.float)(p), C.sf_count_t(frames))
-	case reflect.Float64:
-		n = C.sf_readf_double(f.s, (*C.double)(p), C.sf_count_t(frames))
-	case reflect.Int, reflect.Uint:
-		switch t.Bits() {
-		case 32:
-			n = C.sf_readf_int(f.s, (*C.int)(p), C.sf_count_t(frames))
-		case 16: // doubtful
-			n = C.sf_readf_short(f.s, (*C.short)(p), C.sf_count_t(frames))
-		default:
-			err = errors.New("Unsupported type in read buffer, needs (u)int16, (u)int32, or float type")
-		}
-	default:
-		err = errors.New("Unsupported type in read buffer, needs (u)int16, (u)int32, or float type")
-	}
-	if err != nil {
-		read = -1
-		return
+out must be a slice of int16, uint16, int32, uint32, float32, or float64. The ReadFramesInt16, ReadFramesInt32, ReadFramesFloat32 and ReadFramesFloat64 methods do the same thing without the type switch.*/
+func (f *File) ReadFrames(out interface{}) (read int64, err error) {
+	switch b := out.(type) {
+	case []int16:
+		return f.ReadFramesInt16(b)
+	case []uint16:
+		return f.ReadFramesInt16(*(*[]int16)(unsafe.Pointer(&b)))
+	case []int32:
+		return f.ReadFramesInt32(b)
+	case []uint32:
+		return f.ReadFramesInt32(*(*[]int32)(unsafe.Pointer(&b)))
+	case []float32:
+		return f.ReadFramesFloat32(b)
+	case []float64:
+		return f.ReadFramesFloat64(b)
 	}
+	return -1, errors.New("Unsupported type in read buffer, needs (u)int16, (u)int32, or float type")
+}
 
-	read = int64(n)
-	if read < 0 {
-		err = errors.New(C.GoString(C.sf_strerror(f.s)))
+// readFramesLocked is the type switch of ReadFrames for callers that hold the lock.
+func (f *File) readFramesLocked(out interface{}) (read int64, err error) {
+	switch b := out.(type) {
+	case []int16:
+		return f.readFramesInt16Locked(b)
+	case []uint16:
+		return f.readFramesInt16Locked(*(*[]int16)(unsafe.Pointer(&b)))
+	case []int32:
+		return f.readFramesInt32Locked(b)
+	case []uint32:
+		return f.readFramesInt32Locked(*(*[]int32)(unsafe.Pointer(&b)))
+	case []float32:
+		return f.readFramesFloat32Locked(b)
+	case []float64:
+		return f.readFramesFloat64Locked(b)
 	}
-	return
+	return -1, errors.New("Unsupported type in read buffer, needs (u)int16, (u)int32, or float type")
 }
 
 type StringType C.int
@@ -362,6 +488,10 @@ const (
 
 //The GetString() method returns the specified string if it exists and a NULL pointer otherwise. In addition to the string ids above, First (== Title) and Last (always the same as the highest numbers string id) are also available to allow iteration over all the available string ids.
 func (f *File) GetString(typ StringType) (out string) {
+	if f.lock() != nil {
+		return
+	}
+	defer f.mu.Unlock()
 	// although it's not clear from the docs, sf_get_string doesn't require you to free the string that is returned
 	s := C.sf_get_string(f.s, C.int(typ))
 	if s != nil {
@@ -372,10 +502,14 @@ func (f *File) GetString(typ StringType) (out string) {
 
 //The SetString() method sets the string data in a file. It returns nil on success and non-nil on error.
 func (f *File) SetString(in string, typ StringType) (err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
 	s := C.CString(in)
 	defer C.free(unsafe.Pointer(s))
 	if C.sf_set_string(f.s, C.int(typ), s) != 0 {
-		err = errors.New(C.GoString(C.sf_strerror(f.s)))
+		err = f.error("SetString")
 	}
 	return
 }
@@ -385,49 +519,24 @@ func (f *File) SetString(in string, typ StringType) (err error) {
 //It is important to note that the data type used by the calling program and the data format of the file do not need to be the same. For instance, it is possible to open a 16 bit PCM encoded WAV file and write the data from a []float32. The library seamlessly converts between the two formats on-the-fly.
 //
 //Returns the number of items written (which should be the same as the length of the input parameter). err will be nil, except in case of failure
-
+//
+//in must be a slice of int16, uint16, int32, uint32, float32, or float64. The WriteItemsInt16, WriteItemsInt32, WriteItemsFloat32 and WriteItemsFloat64 methods do the same thing without the type switch.
 func (f *File) WriteItems(in interface{}) (written int64, err error) {
-	t := reflect.TypeOf(in)
-	if t.Kind() != reflect.Array && t.Kind() != reflect.Slice {
-		errors.New("You need to give me an array!")
-	}
-
-	v := reflect.ValueOf(in)
-	l := v.Len()
-	o := v.Slice(0, l)
-	var n C.sf_count_t
-	p := unsafe.Pointer(o.Index(0).Addr().Pointer())
-	switch t.Elem().Kind() {
-	case reflect.Int16, reflect.Uint16:
-		n = C.sf_write_short(f.s, (*C.short)(p), C.sf_count_t(l))
-	case reflect.Int32, reflect.Uint32:
-		n = C.sf_write_int(f.s, (*C.int)(p), C.sf_count_t(l))
-	case reflect.Float32:
-		n = C.sf_write_float(f.s, (*C.float)(p), C.sf_count_t(l))
-	case reflect.Float64:
-		n = C.sf_write_double(f.s, (*C.double)(p), C.sf_count_t(l))
-	case reflect.Int, reflect.Uint:
-		switch t.Bits() {
-		case 32:
-			n = C.sf_write_int(f.s, (*C.int)(p), C.sf_count_t(l))
-		case 16: // doubtful
-			n = C.sf_write_short(f.s, (*C.short)(p), C.sf_count_t(l))
-		default:
-			err = errors.New("Unsupported type in read buffer, needs (u)int16, (u)int32, or float type")
-		}
-	default:
-		err = errors.New("Unsupported type in read buffer, needs (u)int16, (u)int32, or float type")
-	}
-	if err != nil {
-		written = -1
-		return
-	}
-
-	written = int64(n)
-	if int(n) != l {
-		err = errors.New(C.GoString(C.sf_strerror(f.s)))
+	switch b := in.(type) {
+	case []int16:
+		return f.WriteItemsInt16(b)
+	case []uint16:
+		return f.WriteItemsInt16(*(*[]int16)(unsafe.Pointer(&b)))
+	case []int32:
+		return f.WriteItemsInt32(b)
+	case []uint32:
+		return f.WriteItemsInt32(*(*[]int32)(unsafe.Pointer(&b)))
+	case []float32:
+		return f.WriteItemsFloat32(b)
+	case []float64:
+		return f.WriteItemsFloat64(b)
 	}
-	return
+	return -1, errors.New("Unsupported type in written buffer, needs (u)int16, (u)int32, or float type")
 }
 
 //The file write frames function writes the data in the array or slice pointed to by the input argument to the file. The items parameter must be an integer product of the number of channels or an error will occur.
@@ -435,51 +544,302 @@ func (f *File) WriteItems(in interface{}) (written int64, err error) {
 //It is important to note that the data type used by the calling program and the data format of the file do not need to be the same. For instance, it is possible to open a 16 bit PCM encoded WAV file and write the data from a []float32. The library seamlessly converts between the two formats on-the-fly.
 //
 //Returns the number of frames written (which should be the same as the length of the input parameter divided by the number of channels). err wil be nil except in case of failure
+//
+//in must be a slice of int16, uint16, int32, uint32, float32, or float64. The WriteFramesInt16, WriteFramesInt32, WriteFramesFloat32 and WriteFramesFloat64 methods do the same thing without the type switch.
 func (f *File) WriteFrames(in interface{}) (written int64, err error) {
-	t := reflect.TypeOf(in)
-	if t.Kind() != reflect.Array && t.Kind() != reflect.Slice {
-		errors.New("You need to give me an array!")
+	switch b := in.(type) {
+	case []int16:
+		return f.WriteFramesInt16(b)
+	case []uint16:
+		return f.WriteFramesInt16(*(*[]int16)(unsafe.Pointer(&b)))
+	case []int32:
+		return f.WriteFramesInt32(b)
+	case []uint32:
+		return f.WriteFramesInt32(*(*[]int32)(unsafe.Pointer(&b)))
+	case []float32:
+		return f.WriteFramesFloat32(b)
+	case []float64:
+		return f.WriteFramesFloat64(b)
+	}
+	return -1, errors.New("Unsupported type in written buffer, needs (u)int16, (u)int32, or float type")
+}
+
+// ReadItemsInt16 is ReadItems for a []int16, calling sf_read_short directly.
+func (f *File) ReadItemsInt16(out []int16) (read int64, err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	if len(out) == 0 {
+		return 0, nil
+	}
+	n := C.sf_read_short(f.s, (*C.short)(unsafe.Pointer(&out[0])), C.sf_count_t(len(out)))
+	return f.readResult("ReadItemsInt16", n)
+}
+
+// ReadFramesInt16 is ReadFrames for a []int16, calling sf_readf_short directly.
+func (f *File) ReadFramesInt16(out []int16) (read int64, err error) {
+	if err = f.lock(); err != nil {
+		return
 	}
+	defer f.mu.Unlock()
+	return f.readFramesInt16Locked(out)
+}
 
-	v := reflect.ValueOf(in)
-	l := v.Len()
-	o := v.Slice(0, l)
-	frames := l / int(f.Format.Channels)
+func (f *File) readFramesInt16Locked(out []int16) (read int64, err error) {
+	frames := len(out) / int(f.Format.Channels)
 	if frames < 1 {
-		err = io.EOF
+		return 0, io.EOF
+	}
+	n := C.sf_readf_short(f.s, (*C.short)(unsafe.Pointer(&out[0])), C.sf_count_t(frames))
+	return f.readResult("ReadFramesInt16", n)
+}
+
+// WriteItemsInt16 is WriteItems for a []int16, calling sf_write_short directly.
+func (f *File) WriteItemsInt16(in []int16) (written int64, err error) {
+	if err = f.lock(); err != nil {
 		return
 	}
-	var n C.sf_count_t
-	p := unsafe.Pointer(o.Index(0).Addr().Pointer())
-	switch t.Elem().Kind() {
-	case reflect.Int16, reflect.Uint16:
-		n = C.sf_writef_short(f.s, (*C.short)(p), C.sf_count_t(frames))
-	case reflect.Int32, reflect.Uint32:
-		n = C.sf_writef_int(f.s, (*C.int)(p), C.sf_count_t(frames))
-	case reflect.Float32:
-		n = C.sf_writef_float(f.s, (*C.float)(p), C.sf_count_t(frames))
-	case reflect.Float64:
-		n = C.sf_writef_double(f.s, (*C.double)(p), C.sf_count_t(frames))
-	case reflect.Int, reflect.Uint:
-		switch t.Bits() {
-		case 32:
-			n = C.sf_writef_int(f.s, (*C.int)(p), C.sf_count_t(frames))
-		case 16: // doubtful
-			n = C.sf_writef_short(f.s, (*C.short)(p), C.sf_count_t(frames))
-		default:
-			err = errors.New("Unsupported type in written buffer, needs (u)int16, (u)int32, or float type")
-		}
-	default:
-		err = errors.New("Unsupported type in written buffer, needs (u)int16, (u)int32, or float type")
+	defer f.mu.Unlock()
+	if len(in) == 0 {
+		return 0, nil
 	}
-	if err != nil {
-		written = -1
+	n := C.sf_write_short(f.s, (*C.short)(unsafe.Pointer(&in[0])), C.sf_count_t(len(in)))
+	return f.writeResult("WriteItemsInt16", n, len(in))
+}
+
+// WriteFramesInt16 is WriteFrames for a []int16, calling sf_writef_short directly.
+func (f *File) WriteFramesInt16(in []int16) (written int64, err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	return f.writeFramesInt16Locked(in)
+}
+
+func (f *File) writeFramesInt16Locked(in []int16) (written int64, err error) {
+	frames := len(in) / int(f.Format.Channels)
+	if frames < 1 {
+		return 0, io.EOF
+	}
+	n := C.sf_writef_short(f.s, (*C.short)(unsafe.Pointer(&in[0])), C.sf_count_t(frames))
+	return f.writeResult("WriteFramesInt16", n, frames)
+}
+
+// ReadItemsInt32 is ReadItems for a []int32, calling sf_read_int directly.
+func (f *File) ReadItemsInt32(out []int32) (read int64, err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	if len(out) == 0 {
+		return 0, nil
+	}
+	n := C.sf_read_int(f.s, (*C.int)(unsafe.Pointer(&out[0])), C.sf_count_t(len(out)))
+	return f.readResult("ReadItemsInt32", n)
+}
+
+// ReadFramesInt32 is ReadFrames for a []int32, calling sf_readf_int directly.
+func (f *File) ReadFramesInt32(out []int32) (read int64, err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	return f.readFramesInt32Locked(out)
+}
+
+func (f *File) readFramesInt32Locked(out []int32) (read int64, err error) {
+	frames := len(out) / int(f.Format.Channels)
+	if frames < 1 {
+		return 0, io.EOF
+	}
+	n := C.sf_readf_int(f.s, (*C.int)(unsafe.Pointer(&out[0])), C.sf_count_t(frames))
+	return f.readResult("ReadFramesInt32", n)
+}
+
+// WriteItemsInt32 is WriteItems for a []int32, calling sf_write_int directly.
+func (f *File) WriteItemsInt32(in []int32) (written int64, err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	if len(in) == 0 {
+		return 0, nil
+	}
+	n := C.sf_write_int(f.s, (*C.int)(unsafe.Pointer(&in[0])), C.sf_count_t(len(in)))
+	return f.writeResult("WriteItemsInt32", n, len(in))
+}
+
+// WriteFramesInt32 is WriteFrames for a []int32, calling sf_writef_int directly.
+func (f *File) WriteFramesInt32(in []int32) (written int64, err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	return f.writeFramesInt32Locked(in)
+}
+
+func (f *File) writeFramesInt32Locked(in []int32) (written int64, err error) {
+	frames := len(in) / int(f.Format.Channels)
+	if frames < 1 {
+		return 0, io.EOF
+	}
+	n := C.sf_writef_int(f.s, (*C.int)(unsafe.Pointer(&in[0])), C.sf_count_t(frames))
+	return f.writeResult("WriteFramesInt32", n, frames)
+}
+
+// ReadItemsFloat32 is ReadItems for a []float32, calling sf_read_float directly.
+func (f *File) ReadItemsFloat32(out []float32) (read int64, err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	if len(out) == 0 {
+		return 0, nil
+	}
+	n := C.sf_read_float(f.s, (*C.float)(unsafe.Pointer(&out[0])), C.sf_count_t(len(out)))
+	return f.readResult("ReadItemsFloat32", n)
+}
+
+// ReadFramesFloat32 is ReadFrames for a []float32, calling sf_readf_float directly.
+func (f *File) ReadFramesFloat32(out []float32) (read int64, err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	return f.readFramesFloat32Locked(out)
+}
+
+func (f *File) readFramesFloat32Locked(out []float32) (read int64, err error) {
+	frames := len(out) / int(f.Format.Channels)
+	if frames < 1 {
+		return 0, io.EOF
+	}
+	n := C.sf_readf_float(f.s, (*C.float)(unsafe.Pointer(&out[0])), C.sf_count_t(frames))
+	return f.readResult("ReadFramesFloat32", n)
+}
+
+// WriteItemsFloat32 is WriteItems for a []float32, calling sf_write_float directly.
+func (f *File) WriteItemsFloat32(in []float32) (written int64, err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	if len(in) == 0 {
+		return 0, nil
+	}
+	if f.ditherActive(C.SFC_GET_NORM_FLOAT) {
+		return f.writeDitheredFloat32("WriteItemsFloat32", in, false)
+	}
+	n := C.sf_write_float(f.s, (*C.float)(unsafe.Pointer(&in[0])), C.sf_count_t(len(in)))
+	return f.writeResult("WriteItemsFloat32", n, len(in))
+}
+
+// WriteFramesFloat32 is WriteFrames for a []float32, calling sf_writef_float directly.
+func (f *File) WriteFramesFloat32(in []float32) (written int64, err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	return f.writeFramesFloat32Locked(in)
+}
+
+func (f *File) writeFramesFloat32Locked(in []float32) (written int64, err error) {
+	frames := len(in) / int(f.Format.Channels)
+	if frames < 1 {
+		return 0, io.EOF
+	}
+	if f.ditherActive(C.SFC_GET_NORM_FLOAT) {
+		return f.writeDitheredFloat32("WriteFramesFloat32", in, true)
+	}
+	n := C.sf_writef_float(f.s, (*C.float)(unsafe.Pointer(&in[0])), C.sf_count_t(frames))
+	return f.writeResult("WriteFramesFloat32", n, frames)
+}
+
+// ReadItemsFloat64 is ReadItems for a []float64, calling sf_read_double directly.
+func (f *File) ReadItemsFloat64(out []float64) (read int64, err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	if len(out) == 0 {
+		return 0, nil
+	}
+	n := C.sf_read_double(f.s, (*C.double)(unsafe.Pointer(&out[0])), C.sf_count_t(len(out)))
+	return f.readResult("ReadItemsFloat64", n)
+}
+
+// ReadFramesFloat64 is ReadFrames for a []float64, calling sf_readf_double directly.
+func (f *File) ReadFramesFloat64(out []float64) (read int64, err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	return f.readFramesFloat64Locked(out)
+}
+
+func (f *File) readFramesFloat64Locked(out []float64) (read int64, err error) {
+	frames := len(out) / int(f.Format.Channels)
+	if frames < 1 {
+		return 0, io.EOF
+	}
+	n := C.sf_readf_double(f.s, (*C.double)(unsafe.Pointer(&out[0])), C.sf_count_t(frames))
+	return f.readResult("ReadFramesFloat64", n)
+}
+
+// WriteItemsFloat64 is WriteItems for a []float64, calling sf_write_double directly.
+func (f *File) WriteItemsFloat64(in []float64) (written int64, err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	if len(in) == 0 {
+		return 0, nil
+	}
+	if f.ditherActive(C.SFC_GET_NORM_DOUBLE) {
+		return f.writeDitheredFloat64("WriteItemsFloat64", in, false)
+	}
+	n := C.sf_write_double(f.s, (*C.double)(unsafe.Pointer(&in[0])), C.sf_count_t(len(in)))
+	return f.writeResult("WriteItemsFloat64", n, len(in))
+}
+
+// WriteFramesFloat64 is WriteFrames for a []float64, calling sf_writef_double directly.
+func (f *File) WriteFramesFloat64(in []float64) (written int64, err error) {
+	if err = f.lock(); err != nil {
 		return
 	}
+	defer f.mu.Unlock()
+	return f.writeFramesFloat64Locked(in)
+}
 
+func (f *File) writeFramesFloat64Locked(in []float64) (written int64, err error) {
+	frames := len(in) / int(f.Format.Channels)
+	if frames < 1 {
+		return 0, io.EOF
+	}
+	if f.ditherActive(C.SFC_GET_NORM_DOUBLE) {
+		return f.writeDitheredFloat64("WriteFramesFloat64", in, true)
+	}
+	n := C.sf_writef_double(f.s, (*C.double)(unsafe.Pointer(&in[0])), C.sf_count_t(frames))
+	return f.writeResult("WriteFramesFloat64", n, frames)
+}
+
+func (f *File) readResult(op string, n C.sf_count_t) (read int64, err error) {
+	read = int64(n)
+	if read < 0 {
+		err = f.error(op)
+	}
+	return
+}
+
+func (f *File) writeResult(op string, n C.sf_count_t, requested int) (written int64, err error) {
 	written = int64(n)
-	if int(n) != frames {
-		err = errors.New(C.GoString(C.sf_strerror(f.s)))
+	if n > 0 {
+		f.written = true
+		f.updateFrames()
+	}
+	if int(n) != requested {
+		err = f.error(op)
 	}
 	return
 }
@@ -1,8 +1,13 @@
 package sndfile
 
 import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
 	"os"
 	"reflect"
+	"sync"
 	"testing"
 )
 
@@ -246,4 +251,450 @@ func TestError(t *testing.T) {
 	var i Info
 	_, err := Open("nonexistentfile", Read, &i)
 	t.Log(err)
+	var serr *Error
+	if !errors.As(err, &serr) {
+		t.Fatalf("expected *Error, got %T", err)
+	}
+	if serr.Op != "Open" || serr.Path != "nonexistentfile" {
+		t.Errorf("wrong op or path in %#v", serr)
+	}
+	if !errors.Is(err, ErrSystem) {
+		t.Errorf("missing file should be a system error, got code %d", serr.Code)
+	}
+
+	_, err = OpenReader(bytes.NewReader([]byte("this is not a sound file, honest")), Read, &i)
+	if !errors.Is(err, ErrUnrecognisedFormat) {
+		t.Errorf("garbage should be an unrecognised format, got %v", err)
+	}
+	if errors.Is(err, ErrSystem) {
+		t.Error("garbage should not be a system error")
+	}
+}
+
+// a file cut off before its sound data gets one of libsndfile's internal numbers, which should still match ErrMalformedFile
+func TestErrorMalformed(t *testing.T) {
+	b, err := ioutil.ReadFile("test/ok.aiff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var i Info
+	// keep the FORM, COMT and COMM chunks but not SSND
+	_, err = OpenReader(bytes.NewReader(b[:68]), Read, &i)
+	var serr *Error
+	if !errors.As(err, &serr) {
+		t.Fatalf("expected *Error, got %T %v", err, err)
+	}
+	t.Log(serr.Code, err)
+	if !errors.Is(err, ErrMalformedFile) {
+		t.Errorf("truncated file should be malformed, got code %d: %v", serr.Code, err)
+	}
+	if errors.Is(err, ErrUnrecognisedFormat) || errors.Is(err, ErrSystem) {
+		t.Errorf("truncated file matched the wrong sentinel, code %d: %v", serr.Code, err)
+	}
+	if !errors.Is(err, serr.Code) {
+		t.Error("error doesn't match its own code", serr.Code)
+	}
+}
+
+func TestReadFramesTyped(t *testing.T) {
+	var i Info
+	f, e := Open("test/ok.aiff", Read, &i)
+	if e != nil {
+		t.Fatal(e)
+	}
+	_, e = f.Seek(i.Frames/2, Set)
+	if e != nil {
+		t.Fatal(e)
+	}
+	sbuf := make([]int16, 10)
+	r, e := f.ReadFramesInt16(sbuf)
+	if r != 10 || e != nil {
+		t.Errorf("only read %d out of 10 frames %v", r, e)
+	}
+	if !reflect.DeepEqual(sbuf, goldenShortFramesSeekInput()) {
+		t.Errorf("data not as expected! %v vs golden %v", sbuf, goldenShortFramesSeekInput())
+	}
+
+	_, e = f.Seek(i.Frames/2, Set)
+	if e != nil {
+		t.Fatal(e)
+	}
+	ibuf := make([]int32, 10)
+	r, e = f.ReadFramesInt32(ibuf)
+	if r != 10 || e != nil {
+		t.Errorf("only read %d out of 10 frames %v", r, e)
+	}
+	if !reflect.DeepEqual(ibuf, goldenIntFramesSeekInput()) {
+		t.Errorf("data not as expected! %v vs golden %v", ibuf, goldenIntFramesSeekInput())
+	}
+
+	r, e = f.ReadFramesFloat32(nil)
+	if r != 0 || e == nil {
+		t.Errorf("empty read should fail, got %d %v", r, e)
+	}
+	f.Close()
+}
+
+func TestWriteItemsTyped(t *testing.T) {
+	var i Info
+	i.Format = SF_FORMAT_WAV | SF_FORMAT_FLOAT
+	i.Channels = 2
+	i.Samplerate = 44100
+	os.Remove("typed.wav")
+	f, err := Open("typed.wav", ReadWrite, &i)
+	if err != nil {
+		t.Fatal("couldn't open file", err)
+	}
+	out := []float64{0.25, -0.25, 0.5, -0.5, 0.75, -0.75}
+	n, err := f.WriteItemsFloat64(out)
+	if n != int64(len(out)) || err != nil {
+		t.Error("bad write", n, err)
+	}
+	n, err = f.WriteFramesFloat64(out)
+	if n != int64(len(out)/2) || err != nil {
+		t.Error("bad write", n, err)
+	}
+	f.Seek(0, Set)
+	in := make([]float32, len(out)*2)
+	n, err = f.ReadItemsFloat32(in)
+	if n != int64(len(in)) || err != nil {
+		t.Error("bad read", n, err)
+	}
+	for j, v := range in {
+		if float64(v) != out[j%len(out)] {
+			t.Errorf("sample %d was %v not %v", j, v, out[j%len(out)])
+		}
+	}
+	f.Close()
+}
+
+func TestUnsupportedBuffer(t *testing.T) {
+	var i Info
+	f, e := Open("test/ok.aiff", Read, &i)
+	if e != nil {
+		t.Fatal(e)
+	}
+	n, e := f.ReadItems(make([]int8, 10))
+	if n != -1 || e == nil {
+		t.Error("[]int8 should be rejected", n, e)
+	}
+	n, e = f.ReadFrames([10]int16{})
+	if n != -1 || e == nil {
+		t.Error("arrays should be rejected", n, e)
+	}
+	f.Close()
+}
+
+// run with -race
+func TestConcurrentUse(t *testing.T) {
+	var i Info
+	f, err := Open("test/ok.aiff", Read, &i)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			buf := make([]int16, 64)
+			for n := 0; n < 200; n++ {
+				if _, err := f.Seek(int64(g*n), Set); err != nil && err != ErrClosed {
+					t.Error("seek failed", err)
+					return
+				}
+				if _, err := f.ReadFrames(buf); err != nil && err != ErrClosed {
+					t.Error("read failed", err)
+					return
+				}
+				f.GetString(Title)
+				f.GetLoopInfo()
+			}
+		}(g)
+	}
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := f.Close(); err != nil {
+				t.Error("close failed", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestUseAfterClose(t *testing.T) {
+	var i Info
+	f, err := Open("test/ok.aiff", Read, &i)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Close(); err != nil {
+		t.Fatal("close failed", err)
+	}
+	if err = f.Close(); err != nil {
+		t.Error("second close should be a no-op", err)
+	}
+	if _, err = f.ReadFrames(make([]int16, 10)); err != ErrClosed {
+		t.Error("ReadFrames after close", err)
+	}
+	if _, err = f.ReadItemsFloat64(make([]float64, 10)); err != ErrClosed {
+		t.Error("ReadItemsFloat64 after close", err)
+	}
+	if _, err = f.WriteItems(make([]int16, 10)); err != ErrClosed {
+		t.Error("WriteItems after close", err)
+	}
+	if _, err = f.Seek(0, Set); err != ErrClosed {
+		t.Error("Seek after close", err)
+	}
+	if _, err = f.ReadRaw(make([]byte, 10)); err != ErrClosed {
+		t.Error("ReadRaw after close", err)
+	}
+	if err = f.SetString("x", Title); err != ErrClosed {
+		t.Error("SetString after close", err)
+	}
+	if s := f.GetString(Title); s != "" {
+		t.Error("GetString after close", s)
+	}
+	if _, err = f.CalcSignalMax(); err != ErrClosed {
+		t.Error("CalcSignalMax after close", err)
+	}
+	if err = f.Truncate(0); err != ErrClosed {
+		t.Error("Truncate after close", err)
+	}
+	if _, err = f.GetChannelMapInfo(); err != ErrClosed {
+		t.Error("GetChannelMapInfo after close", err)
+	}
+	if f.SetClipping(true) {
+		t.Error("SetClipping after close")
+	}
+	if GenericCmd(f, 0x1000, nil, 0) != 0 {
+		t.Error("GenericCmd after close")
+	}
+}
+
+func fileMagic(t *testing.T, name string) string {
+	b := make([]byte, 4)
+	fh, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fh.Close()
+	if _, err = fh.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestRF64AutoDowngrade(t *testing.T) {
+	i := Info{Channels: 2, Samplerate: 48000, Format: SF_FORMAT_WAV | SF_FORMAT_PCM_24}
+	f, err := OpenRF64Auto("rf64auto.wav", &i)
+	if err != nil {
+		t.Fatal("OpenRF64Auto failed", err)
+	}
+	if i.Format != SF_FORMAT_RF64|SF_FORMAT_PCM_24 {
+		t.Errorf("expected RF64/PCM_24, got %v", i.Format)
+	}
+	f.WriteFramesInt32(make([]int32, 2000))
+	if f.SetRF64AutoDowngrade(false) == nil {
+		t.Error("SetRF64AutoDowngrade succeeded after a write")
+	}
+	f.Close()
+	if m := fileMagic(t, "rf64auto.wav"); m != "RIFF" {
+		t.Errorf("small file not downgraded, magic is %q", m)
+	}
+	var ri Info
+	f, err = Open("rf64auto.wav", Read, &ri)
+	if err != nil {
+		t.Fatal("couldn't read back downgraded file", err)
+	}
+	f.Close()
+	if ri.Format != SF_FORMAT_WAV|SF_FORMAT_PCM_24 || ri.Frames != 1000 {
+		t.Errorf("downgraded file has format %v and %d frames", ri.Format, ri.Frames)
+	}
+
+	i.Format = SF_FORMAT_RF64 | SF_FORMAT_PCM_24
+	f, err = Open("rf64auto.wav", Write, &i)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteFramesInt32(make([]int32, 2000))
+	f.Close()
+	if m := fileMagic(t, "rf64auto.wav"); m != "RF64" {
+		t.Errorf("file without auto downgrade has magic %q", m)
+	}
+}
+
+// largeStore is a virtual file that keeps only its first bytes, so multi-gigabyte files can be written without the disk space or memory.
+type largeStore struct {
+	head      []byte
+	size, pos int64
+}
+
+func (s *largeStore) virtualIo() VirtualIo {
+	return VirtualIo{
+		GetLength: func(ud interface{}) int64 { return s.size },
+		Seek: func(offset int64, whence Whence, ud interface{}) int64 {
+			switch whence {
+			case Set:
+				s.pos = offset
+			case Current:
+				s.pos += offset
+			case End:
+				s.pos = s.size + offset
+			}
+			return s.pos
+		},
+		Read: func(b []byte, ud interface{}) int64 {
+			if s.pos >= s.size {
+				return 0
+			}
+			if int64(len(b)) > s.size-s.pos {
+				b = b[:s.size-s.pos]
+			}
+			for n := range b {
+				b[n] = 0
+			}
+			if s.pos < int64(len(s.head)) {
+				copy(b, s.head[s.pos:])
+			}
+			s.pos += int64(len(b))
+			return int64(len(b))
+		},
+		Write: func(b []byte, ud interface{}) int64 {
+			if s.pos < int64(len(s.head)) {
+				copy(s.head[s.pos:], b)
+			}
+			s.pos += int64(len(b))
+			if s.pos > s.size {
+				s.size = s.pos
+			}
+			return int64(len(b))
+		},
+		Tell: func(ud interface{}) int64 { return s.pos },
+	}
+}
+
+func TestRF64Large(t *testing.T) {
+	if testing.Short() {
+		t.Skip("writes over 4GiB of audio")
+	}
+	s := &largeStore{head: make([]byte, 64*1024)}
+	i := Info{Channels: 2, Samplerate: 48000, Format: SF_FORMAT_RF64 | SF_FORMAT_PCM_16}
+	f, err := OpenVirtual(s.virtualIo(), Write, &i)
+	if err != nil {
+		t.Fatal("couldn't open virtual RF64 file", err)
+	}
+	if err = f.SetRF64AutoDowngrade(true); err != nil {
+		t.Fatal("SetRF64AutoDowngrade failed", err)
+	}
+	buf := make([]byte, 16<<20)
+	var dataSize uint64
+	for dataSize <= 1<<32 {
+		n, err := f.WriteRaw(buf)
+		if err != nil {
+			t.Fatal("WriteRaw failed after", dataSize, err)
+		}
+		dataSize += uint64(n)
+	}
+	f.Close()
+
+	h := s.head
+	if string(h[0:4]) != "RF64" || string(h[8:12]) != "WAVE" {
+		t.Fatalf("large file isn't RF64: %q %q", h[0:4], h[8:12])
+	}
+	if sz := binary.LittleEndian.Uint32(h[4:8]); sz != 0xffffffff {
+		t.Errorf("RIFF size is %#x, expected 0xffffffff", sz)
+	}
+	var ds64, data bool
+	for p := 12; p+8 <= len(h) && !data; {
+		id, size := string(h[p:p+4]), binary.LittleEndian.Uint32(h[p+4:p+8])
+		switch id {
+		case "ds64":
+			ds64 = true
+			riffSize := binary.LittleEndian.Uint64(h[p+8 : p+16])
+			chunkData := binary.LittleEndian.Uint64(h[p+16 : p+24])
+			frames := binary.LittleEndian.Uint64(h[p+24 : p+32])
+			if riffSize != uint64(s.size-8) {
+				t.Errorf("ds64 RIFF size %d, expected %d", riffSize, s.size-8)
+			}
+			if chunkData != dataSize {
+				t.Errorf("ds64 data size %d, expected %d", chunkData, dataSize)
+			}
+			if frames != dataSize/4 {
+				t.Errorf("ds64 frame count %d, expected %d", frames, dataSize/4)
+			}
+		case "data":
+			data = true
+			if size != 0xffffffff {
+				t.Errorf("data chunk size is %#x, expected 0xffffffff", size)
+			}
+		}
+		p += 8 + int(size+size&1)
+	}
+	if !ds64 || !data {
+		t.Errorf("missing chunks, ds64 %v data %v", ds64, data)
+	}
+
+	var ri Info
+	f, err = OpenVirtual(s.virtualIo(), Read, &ri)
+	if err != nil {
+		t.Fatal("couldn't read back large file", err)
+	}
+	f.Close()
+	if ri.Frames != int64(dataSize/4) {
+		t.Errorf("read back %d frames, expected %d", ri.Frames, dataSize/4)
+	}
+}
+
+func TestCurrentInfo(t *testing.T) {
+	i := Info{Channels: 2, Samplerate: 44100, Format: SF_FORMAT_WAV | SF_FORMAT_PCM_16}
+	f, err := Open("currentinfo.wav", ReadWrite, &i)
+	if err != nil {
+		t.Fatal("couldn't open file for write", err)
+	}
+	defer f.Close()
+	if f.Format.Frames != 0 {
+		t.Errorf("new file has %d frames", f.Format.Frames)
+	}
+	f.WriteFramesInt16(make([]int16, 200))
+	if f.Format.Frames != 100 {
+		t.Errorf("expected 100 frames after WriteFrames, got %d", f.Format.Frames)
+	}
+	f.WriteItemsFloat32(make([]float32, 100))
+	if f.Format.Frames != 150 {
+		t.Errorf("expected 150 frames after WriteItems, got %d", f.Format.Frames)
+	}
+	ci, err := f.Info()
+	if err != nil {
+		t.Fatal("Info failed", err)
+	}
+	if ci.Frames != 150 || ci.Channels != 2 || ci.Samplerate != 44100 || ci.Format != i.Format {
+		t.Errorf("unexpected info after writes: %+v", ci)
+	}
+	if f.Format != ci {
+		t.Errorf("Format %+v differs from Info %+v", f.Format, ci)
+	}
+
+	// overwriting doesn't make the file longer
+	f.Seek(0, Set)
+	f.WriteFramesInt16(make([]int16, 20))
+	if ci, _ = f.Info(); ci.Frames != 150 || f.Format.Frames != 150 {
+		t.Errorf("expected 150 frames after overwriting, got %d and %d", ci.Frames, f.Format.Frames)
+	}
+
+	if err = f.Truncate(30); err != nil {
+		t.Fatal("Truncate failed", err)
+	}
+	if f.Format.Frames != 30 {
+		t.Errorf("expected 30 frames after Truncate, got %d", f.Format.Frames)
+	}
+	if ci, _ = f.Info(); ci.Frames != 30 {
+		t.Errorf("expected 30 frames in info after Truncate, got %d", ci.Frames)
+	}
+	f.Close()
+	if _, err = f.Info(); err != ErrClosed {
+		t.Error("Info after Close returned", err)
+	}
 }
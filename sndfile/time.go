@@ -0,0 +1,125 @@
+package sndfile
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// durationOf converts a frame count at the given sample rate to a time.Duration, rounding towards zero to the nanosecond. It doesn't overflow for any count that fits in a Duration.
+func durationOf(frames int64, samplerate int32) time.Duration {
+	sr := int64(samplerate)
+	return time.Duration(frames/sr)*time.Second + time.Duration(frames%sr)*time.Second/time.Duration(sr)
+}
+
+// framesIn converts a time.Duration to the nearest frame at the given sample rate. framesIn(durationOf(n, sr), sr) == n for every n.
+func framesIn(d time.Duration, samplerate int32) int64 {
+	sr := int64(samplerate)
+	secs, rem := int64(d/time.Second), int64(d%time.Second)
+	frames := secs * sr
+	// rem*sr can't overflow: rem is under 2^30 and sr under 2^31
+	if rem >= 0 {
+		return frames + (rem*sr+int64(time.Second)/2)/int64(time.Second)
+	}
+	return frames - (-rem*sr+int64(time.Second)/2)/int64(time.Second)
+}
+
+// Duration returns the length of the audio described by i, or 0 if Samplerate isn't set.
+func (i Info) Duration() time.Duration {
+	if i.Samplerate <= 0 {
+		return 0
+	}
+	return durationOf(i.Frames, i.Samplerate)
+}
+
+// SeekTime is Seek with the offset given as a time, which is rounded to the nearest frame. It returns the new position as a time from the start of the file.
+func (f *File) SeekTime(offset time.Duration, w Whence) (t time.Duration, err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	sr := f.Format.Samplerate
+	if sr <= 0 {
+		return 0, errors.New("SeekTime: file has no sample rate")
+	}
+	frames, err := f.seekLocked(framesIn(offset, sr), w)
+	if err != nil {
+		return 0, err
+	}
+	return durationOf(frames, sr), nil
+}
+
+// Position returns the current position in frames and as a time from the start of the file. This is the write position for files opened for Write and the read position otherwise.
+func (f *File) Position() (frames int64, t time.Duration, err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	if frames, err = f.seekLocked(0, Current); err != nil {
+		return
+	}
+	if sr := f.Format.Samplerate; sr > 0 {
+		t = durationOf(frames, sr)
+	}
+	return
+}
+
+// ReadRange reads the frames from start up to but not including end into buf, which can be any of the slice types ReadFrames accepts. start and end are rounded to the nearest frame, so the range is exactly the frames ReadFrames would see between those points. If buf is too short only its first len(buf)/Channels frames are read. It returns the number of frames read, and leaves the read position after the last of them.
+func (f *File) ReadRange(start, end time.Duration, buf interface{}) (read int64, err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	sr := f.Format.Samplerate
+	if sr <= 0 {
+		return 0, errors.New("ReadRange: file has no sample rate")
+	}
+	if end < start {
+		return 0, fmt.Errorf("ReadRange: end %v is before start %v", end, start)
+	}
+	first, last := framesIn(start, sr), framesIn(end, sr)
+	if _, err = f.seekLocked(first, Set); err != nil {
+		return
+	}
+	if last == first {
+		return 0, nil
+	}
+	buf, err = limitItems(buf, (last-first)*int64(f.Format.Channels))
+	if err != nil {
+		return 0, err
+	}
+	return f.readFramesLocked(buf)
+}
+
+// limitItems shortens buf to at most n items.
+func limitItems(buf interface{}, n int64) (interface{}, error) {
+	switch b := buf.(type) {
+	case []int16:
+		if int64(len(b)) > n {
+			return b[:n], nil
+		}
+	case []uint16:
+		if int64(len(b)) > n {
+			return b[:n], nil
+		}
+	case []int32:
+		if int64(len(b)) > n {
+			return b[:n], nil
+		}
+	case []uint32:
+		if int64(len(b)) > n {
+			return b[:n], nil
+		}
+	case []float32:
+		if int64(len(b)) > n {
+			return b[:n], nil
+		}
+	case []float64:
+		if int64(len(b)) > n {
+			return b[:n], nil
+		}
+	default:
+		return nil, errors.New("Unsupported type in read buffer, needs (u)int16, (u)int32, or float type")
+	}
+	return buf, nil
+}
@@ -0,0 +1,135 @@
+package sndfile
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFramesDuration(t *testing.T) {
+	for _, sr := range []int32{8000, 8012, 11025, 22050, 44100, 48000, 88200, 96000, 176400, 192000, 384000} {
+		for _, n := range []int64{0, 1, 2, 3, 441, 44099, 44100, 44101, 1234567, 1<<32 + 7, -1, -44101} {
+			d := durationOf(n, sr)
+			if back := framesIn(d, sr); back != n {
+				t.Errorf("%d frames at %d Hz is %v, which converts back to %d frames", n, sr, d, back)
+			}
+		}
+	}
+	if d := durationOf(44100*3600*24*365, 44100); d != 365*24*time.Hour {
+		t.Errorf("a year of frames is %v", d)
+	}
+	if n := framesIn(1500*time.Millisecond, 8000); n != 12000 {
+		t.Errorf("1.5s at 8000 Hz is %d frames", n)
+	}
+	if n := framesIn(time.Second/44100*3/2, 44100); n != 1 {
+		t.Errorf("1.5 frames' worth rounds to %d", n)
+	}
+}
+
+func TestInfoDuration(t *testing.T) {
+	i := Info{Frames: 66150, Samplerate: 44100}
+	if d := i.Duration(); d != 1500*time.Millisecond {
+		t.Errorf("expected 1.5s, got %v", d)
+	}
+	i.Samplerate = 0
+	if d := i.Duration(); d != 0 {
+		t.Errorf("expected 0 with no sample rate, got %v", d)
+	}
+	g := goldenInfo()
+	if d := g.Duration(); d != durationOf(24036, 8012) || d.Seconds() < 2.99 || d.Seconds() > 3.01 {
+		t.Errorf("test file is %v long", d)
+	}
+}
+
+func TestSeekTime(t *testing.T) {
+	var i Info
+	f, err := Open("test/ok.aiff", Read, &i)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	pos, err := f.SeekTime(time.Second, Set)
+	if err != nil || pos != time.Second {
+		t.Errorf("SeekTime to 1s returned %v %v", pos, err)
+	}
+	frames, at, err := f.Position()
+	if err != nil || frames != 8012 || at != time.Second {
+		t.Errorf("Position after seeking to 1s is %d frames, %v, %v", frames, at, err)
+	}
+	pos, err = f.SeekTime(-500*time.Millisecond, Current)
+	if err != nil || pos != 500*time.Millisecond {
+		t.Errorf("SeekTime back 0.5s returned %v %v", pos, err)
+	}
+	pos, err = f.SeekTime(0, End)
+	if err != nil || pos != i.Duration() {
+		t.Errorf("SeekTime to the end returned %v %v, expected %v", pos, err, i.Duration())
+	}
+}
+
+func TestReadRange(t *testing.T) {
+	var i Info
+	f, err := Open("test/ok.aiff", Read, &i)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	start, end := durationOf(i.Frames/2, i.Samplerate), durationOf(i.Frames/2+10, i.Samplerate)
+	buf := make([]int16, 100)
+	n, err := f.ReadRange(start, end, buf)
+	if n != 10 || err != nil {
+		t.Fatalf("read %d frames instead of 10: %v", n, err)
+	}
+	if !reflect.DeepEqual(buf[:10], goldenShortFramesSeekInput()) {
+		t.Errorf("data not as expected! %v vs golden %v", buf[:10], goldenShortFramesSeekInput())
+	}
+
+	small := make([]int32, 4)
+	if n, err = f.ReadRange(start, end, small); n != 4 || err != nil {
+		t.Errorf("read %d frames into a 4 frame buffer: %v", n, err)
+	}
+	if !reflect.DeepEqual(small, goldenIntFramesSeekInput()[:4]) {
+		t.Errorf("data not as expected! %v vs golden %v", small, goldenIntFramesSeekInput()[:4])
+	}
+
+	if n, err = f.ReadRange(end, start, buf); err == nil {
+		t.Error("ReadRange accepted end before start")
+	}
+	if n, err = f.ReadRange(start, start, buf); n != 0 || err != nil {
+		t.Errorf("empty range read %d frames: %v", n, err)
+	}
+	if _, err = f.ReadRange(start, end, []byte{}); err == nil {
+		t.Error("ReadRange accepted a []byte")
+	}
+}
+
+// ReadRange seeks and reads as one operation, so a goroutine moving the position can't change what it reads
+func TestReadRangeConcurrent(t *testing.T) {
+	var i Info
+	f, err := Open("test/ok.aiff", Read, &i)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	start, end := durationOf(i.Frames/2, i.Samplerate), durationOf(i.Frames/2+10, i.Samplerate)
+	done := make(chan bool)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				f.Seek(0, Set)
+			}
+		}
+	}()
+	defer close(done)
+	buf := make([]int16, 10)
+	for n := 0; n < 1000; n++ {
+		if r, err := f.ReadRange(start, end, buf); r != 10 || err != nil {
+			t.Fatalf("read %d frames instead of 10: %v", r, err)
+		}
+		if !reflect.DeepEqual(buf, goldenShortFramesSeekInput()) {
+			t.Fatalf("read %d: data not as expected! %v vs golden %v", n, buf, goldenShortFramesSeekInput())
+		}
+	}
+}
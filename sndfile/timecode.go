@@ -0,0 +1,194 @@
+package sndfile
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Timecode frame rates.
+type FrameRate int
+
+const (
+	FPS23976  FrameRate = iota + 1 // 24000/1001 frames per second, counted as 24
+	FPS24                          // film
+	FPS25                          // PAL
+	FPS2997DF                      // 30000/1001 frames per second, drop frame
+	FPS30                          // 30 frames per second, non drop
+)
+
+// nominal returns the frame count that makes up one timecode second.
+func (r FrameRate) nominal() int64 {
+	switch r {
+	case FPS23976, FPS24:
+		return 24
+	case FPS25:
+		return 25
+	case FPS2997DF, FPS30:
+		return 30
+	}
+	return 0
+}
+
+// ratio returns the real frame rate as a fraction.
+func (r FrameRate) ratio() (num, den int64) {
+	switch r {
+	case FPS23976:
+		return 24000, 1001
+	case FPS2997DF:
+		return 30000, 1001
+	}
+	return r.nominal(), 1
+}
+
+func (r FrameRate) String() string {
+	switch r {
+	case FPS23976:
+		return "23.976"
+	case FPS24:
+		return "24"
+	case FPS25:
+		return "25"
+	case FPS2997DF:
+		return "29.97DF"
+	case FPS30:
+		return "30"
+	}
+	return fmt.Sprintf("FrameRate(%d)", int(r))
+}
+
+// Timecode is an SMPTE time of day, hh:mm:ss:ff, at a given frame rate.
+type Timecode struct {
+	Hours, Minutes, Seconds, Frames int
+	Rate                            FrameRate
+}
+
+// String formats t as hh:mm:ss:ff, or hh:mm:ss;ff for drop frame timecode.
+func (t Timecode) String() string {
+	sep := ":"
+	if t.Rate == FPS2997DF {
+		sep = ";"
+	}
+	return fmt.Sprintf("%02d:%02d:%02d%s%02d", t.Hours, t.Minutes, t.Seconds, sep, t.Frames)
+}
+
+// Valid reports whether every field of t is in range for its frame rate. In drop frame timecode, frames 0 and 1 don't exist at the start of each minute except every tenth.
+func (t Timecode) Valid() bool {
+	n := int(t.Rate.nominal())
+	if n == 0 || t.Hours < 0 || t.Hours > 23 || t.Minutes < 0 || t.Minutes > 59 || t.Seconds < 0 || t.Seconds > 59 || t.Frames < 0 || t.Frames >= n {
+		return false
+	}
+	if t.Rate == FPS2997DF && t.Seconds == 0 && t.Frames < 2 && t.Minutes%10 != 0 {
+		return false
+	}
+	return true
+}
+
+// ParseTimecode reads a timecode written as hh:mm:ss:ff. A semicolon or period before the frames is accepted too, as drop frame timecode is often written that way.
+func ParseTimecode(s string, rate FrameRate) (t Timecode, err error) {
+	var sep byte
+	t.Rate = rate
+	if _, err = fmt.Sscanf(s, "%2d:%2d:%2d%c%2d", &t.Hours, &t.Minutes, &t.Seconds, &sep, &t.Frames); err != nil {
+		return Timecode{}, fmt.Errorf("ParseTimecode: %q is not hh:mm:ss:ff: %v", s, err)
+	}
+	if len(s) != 11 || (sep != ':' && sep != ';' && sep != '.') {
+		return Timecode{}, fmt.Errorf("ParseTimecode: %q is not hh:mm:ss:ff", s)
+	}
+	if !t.Valid() {
+		return Timecode{}, fmt.Errorf("ParseTimecode: %q is out of range at %v fps", s, rate)
+	}
+	return
+}
+
+// frameCount returns the number of frames since midnight.
+func (t Timecode) frameCount() int64 {
+	n := t.Rate.nominal()
+	count := (int64(t.Hours)*3600+int64(t.Minutes)*60+int64(t.Seconds))*n + int64(t.Frames)
+	if t.Rate == FPS2997DF {
+		minutes := int64(t.Hours)*60 + int64(t.Minutes)
+		count -= 2 * (minutes - minutes/10)
+	}
+	return count
+}
+
+// timecodeAt is the inverse of frameCount. count wraps at midnight.
+func timecodeAt(count int64, rate FrameRate) (t Timecode) {
+	n := rate.nominal()
+	t.Rate = rate
+	if rate == FPS2997DF {
+		const perTen, perMinute = 17982, 1798 // frames in ten minutes and in a dropped minute
+		count %= 24 * 6 * perTen
+		tens, rem := count/perTen, count%perTen
+		count += 18 * tens
+		if rem >= 2 {
+			count += 2 * ((rem - 2) / perMinute)
+		}
+	}
+	count %= 24 * 3600 * n
+	t.Frames = int(count % n)
+	secs := count / n
+	t.Seconds = int(secs % 60)
+	t.Minutes = int(secs / 60 % 60)
+	t.Hours = int(secs / 3600)
+	return
+}
+
+// Timecode converts the TimeReference of bi, the start of the recording in samples since midnight, to a timecode. The frame is rounded down, so the result is the timecode of the frame the recording starts in.
+func (bi *BroadcastInfo) Timecode(samplerate int32, rate FrameRate) (t Timecode, err error) {
+	num, den := rate.ratio()
+	if num == 0 {
+		return t, fmt.Errorf("Timecode: unknown frame rate %v", rate)
+	}
+	if samplerate <= 0 {
+		return t, fmt.Errorf("Timecode: sample rate %d must be positive", samplerate)
+	}
+	// samples * num / (samplerate * den), split so that a day's worth of samples at any rate can't overflow
+	d := int64(samplerate) * den
+	s := int64(bi.TimeReference % uint64(24*3600*d))
+	count := s/d*num + s%d*num/d
+	return timecodeAt(count, rate), nil
+}
+
+// SetTimecode sets the TimeReference of bi to the first sample of timecode t.
+func (bi *BroadcastInfo) SetTimecode(t Timecode, samplerate int32) error {
+	if !t.Valid() {
+		return fmt.Errorf("SetTimecode: %v is not a valid timecode at %v fps", t, t.Rate)
+	}
+	if samplerate <= 0 {
+		return fmt.Errorf("SetTimecode: sample rate %d must be positive", samplerate)
+	}
+	num, den := t.Rate.ratio()
+	// round up, so the sample found is the first one inside the frame
+	count := t.frameCount()
+	total := count/num*int64(samplerate)*den + (count%num*int64(samplerate)*den+num-1)/num
+	bi.TimeReference = uint64(total)
+	return nil
+}
+
+// StartTimecode returns the timecode at which a Broadcast WAV file starts, worked out from its time reference and sample rate.
+func (f *File) StartTimecode(rate FrameRate) (t Timecode, err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	bi, ok := f.getBroadcastInfoLocked()
+	if !ok {
+		return t, errors.New("StartTimecode: file has no broadcast info")
+	}
+	return bi.Timecode(f.Format.Samplerate, rate)
+}
+
+// SetStartTimecode stores t as the time reference in the broadcast info of a file being written, keeping any other broadcast info already set.
+func (f *File) SetStartTimecode(t Timecode) (err error) {
+	if err = f.lock(); err != nil {
+		return
+	}
+	defer f.mu.Unlock()
+	bi, ok := f.getBroadcastInfoLocked()
+	if !ok {
+		bi = new(BroadcastInfo)
+	}
+	if err = bi.SetTimecode(t, f.Format.Samplerate); err != nil {
+		return
+	}
+	return f.setBroadcastInfoLocked(bi)
+}
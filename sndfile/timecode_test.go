@@ -0,0 +1,124 @@
+package sndfile
+
+import (
+	"os"
+	"testing"
+)
+
+func TestTimecodeDropFrame(t *testing.T) {
+	tests := []struct {
+		count int64
+		tc    string
+	}{
+		{0, "00:00:00;00"},
+		{1799, "00:00:59;29"},
+		{1800, "00:01:00;02"},
+		{17981, "00:09:59;29"},
+		{17982, "00:10:00;00"},
+		{107892, "01:00:00;00"},
+		{24*107892 - 1, "23:59:59;29"},
+		{24 * 107892, "00:00:00;00"},
+	}
+	for _, test := range tests {
+		tc := timecodeAt(test.count, FPS2997DF)
+		if tc.String() != test.tc {
+			t.Errorf("frame %d: expected %v got %v", test.count, test.tc, tc)
+		}
+		if c := tc.frameCount(); c != test.count%(24*107892) {
+			t.Errorf("%v is frame %d, expected %d", tc, c, test.count)
+		}
+	}
+	for c := int64(0); c < 2*17982; c++ {
+		tc := timecodeAt(c, FPS2997DF)
+		if !tc.Valid() || tc.frameCount() != c {
+			t.Fatalf("frame %d gives %v, valid %v, which is frame %d", c, tc, tc.Valid(), tc.frameCount())
+		}
+	}
+}
+
+func TestParseTimecode(t *testing.T) {
+	tc, err := ParseTimecode("10:00:00:00", FPS25)
+	if err != nil || tc != (Timecode{10, 0, 0, 0, FPS25}) {
+		t.Errorf("got %v %v", tc, err)
+	}
+	tc, err = ParseTimecode("01:02:03;04", FPS2997DF)
+	if err != nil || tc != (Timecode{1, 2, 3, 4, FPS2997DF}) || tc.String() != "01:02:03;04" {
+		t.Errorf("got %v %v", tc, err)
+	}
+	for _, s := range []string{"", "10:00:00", "10:00:00:25", "24:00:00:00", "00:01:00;00", "1:00:00:00", "10:00:00:00:00"} {
+		rate := FPS25
+		if s == "00:01:00;00" {
+			rate = FPS2997DF
+		}
+		if tc, err := ParseTimecode(s, rate); err == nil {
+			t.Errorf("ParseTimecode(%q) accepted, got %v", s, tc)
+		}
+	}
+}
+
+func TestBroadcastTimecode(t *testing.T) {
+	tests := []struct {
+		tc         Timecode
+		samplerate int32
+		ref        uint64
+	}{
+		{Timecode{10, 0, 0, 0, FPS25}, 48000, 36000 * 48000},
+		{Timecode{10, 0, 0, 1, FPS25}, 48000, 36000*48000 + 1920},
+		{Timecode{1, 0, 0, 0, FPS24}, 96000, 3600 * 96000},
+		{Timecode{1, 0, 0, 0, FPS30}, 44100, 3600 * 44100},
+		// 107892 frames of 1001/30000s is 172799827.2 samples, the frame starts at the next one
+		{Timecode{1, 0, 0, 0, FPS2997DF}, 48000, 172799828},
+		// 86400 frames of 1001/24000s
+		{Timecode{1, 0, 0, 0, FPS23976}, 48000, 86400 * 2002},
+	}
+	for _, test := range tests {
+		var bi BroadcastInfo
+		if err := bi.SetTimecode(test.tc, test.samplerate); err != nil {
+			t.Fatal(err)
+		}
+		if bi.TimeReference != test.ref {
+			t.Errorf("%v at %d Hz: time reference %d, expected %d", test.tc, test.samplerate, bi.TimeReference, test.ref)
+		}
+		tc, err := bi.Timecode(test.samplerate, test.tc.Rate)
+		if err != nil || tc != test.tc {
+			t.Errorf("time reference %d at %d Hz: got %v %v, expected %v", bi.TimeReference, test.samplerate, tc, err, test.tc)
+		}
+		// the last sample of the previous frame
+		bi.TimeReference--
+		if tc, _ := bi.Timecode(test.samplerate, test.tc.Rate); tc == test.tc {
+			t.Errorf("time reference %d is still in %v", bi.TimeReference, tc)
+		}
+	}
+	var bi BroadcastInfo
+	if bi.SetTimecode(Timecode{10, 0, 0, 30, FPS25}, 48000) == nil {
+		t.Error("SetTimecode accepted frame 30 at 25fps")
+	}
+	if _, err := bi.Timecode(48000, FrameRate(0)); err == nil {
+		t.Error("Timecode accepted an unknown frame rate")
+	}
+}
+
+func TestStartTimecode(t *testing.T) {
+	defer os.Remove("timecode.wav")
+	i := Info{Channels: 1, Samplerate: 48000, Format: SF_FORMAT_WAV | SF_FORMAT_PCM_16}
+	f, err := Open("timecode.wav", Write, &i)
+	if err != nil {
+		t.Fatal("couldn't open file for write", err)
+	}
+	start := Timecode{9, 59, 30, 12, FPS25}
+	if err = f.SetStartTimecode(start); err != nil {
+		t.Fatal("SetStartTimecode failed", err)
+	}
+	f.WriteItemsInt16(make([]int16, 480))
+	f.Close()
+
+	f, err = Open("timecode.wav", Read, &i)
+	if err != nil {
+		t.Fatal("couldn't open file for read", err)
+	}
+	defer f.Close()
+	tc, err := f.StartTimecode(FPS25)
+	if err != nil || tc != start {
+		t.Errorf("expected %v, got %v %v", start, tc, err)
+	}
+}
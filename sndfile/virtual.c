@@ -3,38 +3,40 @@
 #include <stdlib.h>
 #include "_cgo_export.h"
 
+#define HANDLE(user_data) (((gsf_virtual_io *)(user_data))->handle)
+
 sf_count_t  gocall_get_filelen (void *user_data) {
 	// printf("filelen %p\n", gsfLen);
-	return gsfLen(user_data);
+	return gsfLen(HANDLE(user_data));
 }
 
 sf_count_t  gocall_seek (sf_count_t offset, int whence, void *user_data) {
 	// printf("seek %p\n", gsfSeek);
-	return gsfSeek(offset, whence, user_data);
+	return gsfSeek(offset, whence, HANDLE(user_data));
 }
 
 sf_count_t  gocall_read        (void *ptr, sf_count_t count, void *user_data) {
 	// printf("read %p\n", gsfWrite);
-	return gsfRead(ptr, count, user_data);
+	return gsfRead(ptr, count, HANDLE(user_data));
 }
 
 sf_count_t  gocall_write       (const void *ptr, sf_count_t count, void *user_data) {
 	// printf("write %p\n", gsfWrite);
-	return gsfWrite((void *)ptr, count, user_data);
+	return gsfWrite((void *)ptr, count, HANDLE(user_data));
 }
 
 sf_count_t  gocall_tell        (void *user_data) {
 	// printf("tell %p\n", gsfTell);
-	return gsfTell(user_data);
+	return gsfTell(HANDLE(user_data));
 }
 
-SF_VIRTUAL_IO* virtualio() {
-	SF_VIRTUAL_IO *svi = malloc(sizeof(SF_VIRTUAL_IO));
-	svi->get_filelen = gocall_get_filelen;
-	svi->seek = gocall_seek;
-	svi->read = gocall_read;
-	svi->write = gocall_write;
-	svi->tell = gocall_tell;
+gsf_virtual_io* virtualio(uintptr_t handle) {
+	gsf_virtual_io *svi = malloc(sizeof(gsf_virtual_io));
+	svi->io.get_filelen = gocall_get_filelen;
+	svi->io.seek = gocall_seek;
+	svi->io.read = gocall_read;
+	svi->io.write = gocall_write;
+	svi->io.tell = gocall_tell;
+	svi->handle = handle;
 	return svi;
 }
-	
\ No newline at end of file
@@ -1,11 +1,14 @@
 package sndfile
 
+// #include <stdlib.h>
 // #include <sndfile.h>
 // #include "virtual.h"
 import "C"
 import (
 	"errors"
+	"io"
 	"runtime"
+	"sync"
 	"unsafe"
 )
 
@@ -16,26 +19,114 @@ type VIO_write func([]byte, interface{}) int64
 type VIO_tell func(interface{}) int64
 
 // Opens a soundfile from a virtual file I/O context which is provided by the caller. This is usually used to interface libsndfile to a stream or buffer based system. Apart from the c and user_data parameters this function behaves like sf_open.
-// THIS PART OF THE PACKAGE IS EXPERIMENTAL. Don't use it yet.
+// The callbacks are looked up through a handle registry rather than handing a Go pointer to libsndfile, and are released when the File is closed.
 func OpenVirtual(v VirtualIo, mode Mode, info *Info) (f *File, err error) {
-	c := C.virtualio()
-	var vp virtualIo
-	vp.v = &v
-	vp.c = c
+	if info == nil {
+		return nil, errors.New("nil pointer passed to open")
+	}
+	vp := newVirtualIo(&v)
 	f = new(File)
+	f.mode = mode
 	ci := info.toCinfo()
-	f.s = C.sf_open_virtual(c, C.int(mode), ci, unsafe.Pointer(&vp))
+	f.s = C.sf_open_virtual(&vp.c.io, C.int(mode), ci, unsafe.Pointer(vp.c))
 	if f.s != nil {
-		f.virtual = &vp
+		f.virtual = vp
 		f.Format = fromCinfo(ci)
 		*info = f.Format
 	} else {
-		err = errors.New(C.GoString(C.sf_strerror(nil)))
+		err = newError("OpenVirtual", "", nil)
+		vp.free()
 	}
 	runtime.SetFinalizer(f, (*File).Close)
 	return
 }
 
+// OpenReader opens a sound file whose contents are read from r, which may be an *os.File, a *bytes.Reader, an *io.SectionReader or anything else that can seek. mode must be Read; use OpenReadWriteSeeker to write. The info argument is treated the same way as in Open().
+func OpenReader(r io.ReadSeeker, mode Mode, info *Info) (f *File, err error) {
+	if mode != Read {
+		return nil, errors.New("OpenReader only supports Read mode, use OpenReadWriteSeeker")
+	}
+	var v VirtualIo
+	v.GetLength = seekerLength
+	v.Seek = seekerSeek
+	v.Read = readerRead
+	v.Write = readerWrite
+	v.Tell = seekerTell
+	v.UserData = r
+	return OpenVirtual(v, mode, info)
+}
+
+// OpenReadWriteSeeker opens a sound file backed by rws in any mode. The mode and info arguments, and the return values, are the same as for Open().
+func OpenReadWriteSeeker(rws io.ReadWriteSeeker, mode Mode, info *Info) (f *File, err error) {
+	var v VirtualIo
+	v.GetLength = seekerLength
+	v.Seek = seekerSeek
+	v.Read = readerRead
+	v.Write = writerWrite
+	v.Tell = seekerTell
+	v.UserData = rws
+	return OpenVirtual(v, mode, info)
+}
+
+// the VirtualIo callbacks return -1 on error, which is what libsndfile expects from the equivalent stdio calls
+func seekerLength(ud interface{}) int64 {
+	s := ud.(io.Seeker)
+	cur, err := s.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return -1
+	}
+	end, err := s.Seek(0, io.SeekEnd)
+	if err != nil {
+		return -1
+	}
+	if _, err = s.Seek(cur, io.SeekStart); err != nil {
+		return -1
+	}
+	return end
+}
+
+func seekerSeek(offset int64, whence Whence, ud interface{}) int64 {
+	var w int
+	switch whence {
+	case Set:
+		w = io.SeekStart
+	case Current:
+		w = io.SeekCurrent
+	case End:
+		w = io.SeekEnd
+	default:
+		return -1
+	}
+	o, err := ud.(io.Seeker).Seek(offset, w)
+	if err != nil {
+		return -1
+	}
+	return o
+}
+
+func seekerTell(ud interface{}) int64 {
+	o, err := ud.(io.Seeker).Seek(0, io.SeekCurrent)
+	if err != nil {
+		return -1
+	}
+	return o
+}
+
+func readerRead(b []byte, ud interface{}) int64 {
+	// libsndfile treats a short read as end of file, so keep reading until b is full
+	n, _ := io.ReadFull(ud.(io.Reader), b)
+	return int64(n)
+}
+
+func readerWrite(b []byte, ud interface{}) int64 {
+	return 0
+}
+
+func writerWrite(b []byte, ud interface{}) int64 {
+	n, _ := ud.(io.Writer).Write(b)
+	return int64(n)
+}
+
 // You must provide the following:
 //UserData is the virtual file context. It is opaque to this layer.
 //GetLength returns the length of the virtual file in BYTES
@@ -52,42 +143,85 @@ type VirtualIo struct {
 	UserData  interface{}
 }
 
+// virtualIo ties a registry handle to the C callback table that carries it as user_data.
 type virtualIo struct {
-	v *VirtualIo
-	c *C.SF_VIRTUAL_IO
+	h uintptr
+	c *C.gsf_virtual_io
+}
+
+var (
+	virtualLock    sync.Mutex
+	virtualNext    uintptr
+	virtualHandles = make(map[uintptr]*VirtualIo)
+)
+
+func newVirtualIo(v *VirtualIo) *virtualIo {
+	virtualLock.Lock()
+	virtualNext++
+	h := virtualNext
+	virtualHandles[h] = v
+	virtualLock.Unlock()
+	return &virtualIo{h, C.virtualio(C.uintptr_t(h))}
+}
+
+// free drops the handle from the registry and releases the C callback table. It must only be called once libsndfile is done with the file.
+func (vp *virtualIo) free() {
+	virtualLock.Lock()
+	delete(virtualHandles, vp.h)
+	virtualLock.Unlock()
+	C.free(unsafe.Pointer(vp.c))
+	vp.c = nil
+}
+
+// lookupVirtual finds the VirtualIo behind a handle. A callback for a handle that isn't registered can't be served, and the callbacks report that to libsndfile as a failed call rather than panicking across the C stack.
+func lookupVirtual(h uintptr) (*VirtualIo, bool) {
+	virtualLock.Lock()
+	defer virtualLock.Unlock()
+	v, ok := virtualHandles[h]
+	return v, ok
 }
 
 //export gsfLen
-func gsfLen(user_data unsafe.Pointer) int64 {
-	l := (*virtualIo)(user_data)
-	return l.v.GetLength(l.v.UserData)
+func gsfLen(h uintptr) int64 {
+	v, ok := lookupVirtual(h)
+	if !ok {
+		return -1
+	}
+	return v.GetLength(v.UserData)
 }
 
 //export gsfSeek
-func gsfSeek(i int64, w Whence, user_data unsafe.Pointer) int64 {
-	if user_data == nil {
-		panic("nil ud")
+func gsfSeek(i int64, w Whence, h uintptr) int64 {
+	v, ok := lookupVirtual(h)
+	if !ok {
+		return -1
 	}
-	l := (*virtualIo)(user_data)
-	return l.v.Seek(i, w, l.v.UserData)
+	return v.Seek(i, w, v.UserData)
 }
 
 //export gsfRead
-func gsfRead(ptr unsafe.Pointer, i int64, user_data unsafe.Pointer) int64 {
-	l := (*virtualIo)(user_data)
-	b := (*[1 << 30]byte)(ptr)[0:i]
-	return l.v.Read(b, l.v.UserData)
+func gsfRead(ptr unsafe.Pointer, i int64, h uintptr) int64 {
+	v, ok := lookupVirtual(h)
+	if !ok || i <= 0 {
+		return 0
+	}
+	return v.Read(unsafe.Slice((*byte)(ptr), int(i)), v.UserData)
 }
 
 //export gsfWrite
-func gsfWrite(ptr unsafe.Pointer, i int64, user_data unsafe.Pointer) int64 {
-	l := (*virtualIo)(user_data)
-	b := (*[1 << 30]byte)(ptr)[0:i]
-	return l.v.Write(b, l.v.UserData)
+func gsfWrite(ptr unsafe.Pointer, i int64, h uintptr) int64 {
+	v, ok := lookupVirtual(h)
+	if !ok || i <= 0 {
+		return 0
+	}
+	return v.Write(unsafe.Slice((*byte)(ptr), int(i)), v.UserData)
 }
 
 //export gsfTell
-func gsfTell(user_data unsafe.Pointer) int64 {
-	l := (*virtualIo)(user_data)
-	return l.v.Tell(l.v.UserData)
+func gsfTell(h uintptr) int64 {
+	v, ok := lookupVirtual(h)
+	if !ok {
+		return -1
+	}
+	return v.Tell(v.UserData)
 }
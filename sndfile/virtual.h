@@ -1,6 +1,7 @@
 #ifndef GOSNDFILE_VIRTUAL
 #define GOSNDFILE_VIRTUAL
 
+#include <stdint.h>
 #include <sndfile.h>
 
 sf_count_t  gocall_get_filelen (void *user_data) ;
@@ -9,6 +10,12 @@ sf_count_t  gocall_read        (void *ptr, sf_count_t count, void *user_data) ;
 sf_count_t  gocall_write       (const void *ptr, sf_count_t count, void *user_data) ;
 sf_count_t  gocall_tell        (void *user_data) ;
 
-SF_VIRTUAL_IO *virtualio();
+// the callbacks and the registry handle for a virtual file live in C memory so that no Go pointer is ever passed as user_data
+typedef struct {
+	SF_VIRTUAL_IO io;
+	uintptr_t handle;
+} gsf_virtual_io;
+
+gsf_virtual_io *virtualio(uintptr_t handle);
 
 #endif
\ No newline at end of file
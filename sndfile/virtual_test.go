@@ -1,7 +1,9 @@
 package sndfile
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"reflect"
 	"testing"
@@ -103,6 +105,7 @@ func TestVirtualRead(t *testing.T) {
 	if err != nil {
 		t.Fatalf("error from OpenVirtual %v", err)
 	}
+	defer vf.Close()
 	if !reflect.DeepEqual(i, goldenInfo()) {
 		t.Errorf("info struct not as expected! %v vs. golden %v", i, goldenInfo())
 	}
@@ -349,3 +352,118 @@ func TestVirtualWrite(t *testing.T) {
 		t.Errorf("length in samples not as expected! %d vs. expected %d", ri.Frames, len(out)/2)
 	}
 }
+
+func TestOpenReader(t *testing.T) {
+	b, err := ioutil.ReadFile("test/ok.aiff")
+	if err != nil {
+		t.Fatalf("couldn't read input file %s", err)
+	}
+
+	var i Info
+	f, err := OpenReader(bytes.NewReader(b), Read, &i)
+	if err != nil {
+		t.Fatalf("error from OpenReader %v", err)
+	}
+	if !reflect.DeepEqual(i, goldenInfo()) {
+		t.Errorf("info struct not as expected! %v vs. golden %v", i, goldenInfo())
+	}
+
+	_, err = f.Seek(i.Frames/2, Current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]int16, 10)
+	r, err := f.ReadFrames(buf)
+	if r != 10 || err != nil {
+		t.Errorf("only read %d out of 10 items %v", r, err)
+	}
+	if !reflect.DeepEqual(buf, goldenShortFramesSeekInput()) {
+		t.Errorf("data not as expected! %v vs golden %v", buf, goldenShortFramesSeekInput())
+	}
+	f.Close()
+
+	_, err = OpenReader(bytes.NewReader(b), Write, &i)
+	if err == nil {
+		t.Error("OpenReader should refuse to open for writing")
+	}
+}
+
+func TestOpenReadWriteSeeker(t *testing.T) {
+	os.Remove("rwseeker.aiff")
+	osf, err := os.Create("rwseeker.aiff")
+	if err != nil {
+		t.Fatalf("couldn't create output file %s", err)
+	}
+
+	var i Info
+	i.Samplerate = 44100
+	i.Channels = 2
+	i.Format = SF_FORMAT_AIFF | SF_FORMAT_PCM_16
+	f, err := OpenReadWriteSeeker(osf, Write, &i)
+	if err != nil {
+		t.Fatalf("error from OpenReadWriteSeeker %v", err)
+	}
+	out := []int16{1, 2, 3, 4, 5, 6, 7, 8}
+	written, err := f.WriteFrames(out)
+	if written != 4 || err != nil {
+		t.Errorf("unexpected written frame count %d not 4 %v", written, err)
+	}
+	err = f.Close()
+	if err != nil {
+		t.Errorf("close failed %s", err)
+	}
+
+	_, err = osf.Seek(0, os.SEEK_SET)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ri Info
+	f, err = OpenReader(osf, Read, &ri)
+	if err != nil {
+		t.Fatalf("error reopening with OpenReader %v", err)
+	}
+	if ri.Frames != 4 || ri.Channels != 2 {
+		t.Errorf("info not as expected %v", ri)
+	}
+	in := make([]int16, 8)
+	n, err := f.ReadFrames(in)
+	if n != 4 || err != nil {
+		t.Errorf("bad read %d %v", n, err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("data not as expected! %v vs %v", in, out)
+	}
+	f.Close()
+	osf.Close()
+}
+
+// every virtual file must give its registry handle back when closed, including ones that failed to open
+func TestVirtualHandleRelease(t *testing.T) {
+	b, err := ioutil.ReadFile("test/ok.aiff")
+	if err != nil {
+		t.Fatalf("couldn't read input file %s", err)
+	}
+	// other tests may still hold handles, so only count the ones added here
+	virtualLock.Lock()
+	before := len(virtualHandles)
+	virtualLock.Unlock()
+	for n := 0; n < 100; n++ {
+		var i Info
+		f, err := OpenReader(bytes.NewReader(b), Read, &i)
+		if err != nil {
+			t.Fatalf("error from OpenReader %v", err)
+		}
+		f.Close()
+	}
+	var i Info
+	_, err = OpenReader(bytes.NewReader([]byte("not a sound file")), Read, &i)
+	if err == nil {
+		t.Error("expected error opening garbage")
+	}
+	virtualLock.Lock()
+	l := len(virtualHandles)
+	virtualLock.Unlock()
+	if l != before {
+		t.Errorf("%d virtual handles leaked", l-before)
+	}
+}